@@ -0,0 +1,308 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Team analytics ---
+//
+// Cross country is scored on a team's first five runners, with the sixth and
+// seventh pushing other teams' scorers back, so the numbers that matter are
+// how tightly those runners finish: the 1-5 and 1-7 spreads, the average of
+// the top five, and the gap from the fifth runner to the sixth. Each meet's
+// analytics use the times as run; the season trend converts every time to 5k
+// first so meets of different lengths line up. Privacy applies as for
+// /api/results: a hidden athlete's time still counts but isn't named. Nor
+// is an initials-only athlete's, since a place in a team's pack would give
+// away the gender their privacy setting keeps out of public view.
+
+const (
+	scoringRunners = 5
+	teamRunners    = 7
+)
+
+// PackStats are a team's pack numbers for one race. Any that need more
+// runners than finished are left out.
+type PackStats struct {
+	Runners     int    `json:"runners"`
+	Top5Average string `json:"top5_average,omitempty"`
+	Spread15    string `json:"spread_1_5,omitempty"`
+	Spread17    string `json:"spread_1_7,omitempty"`
+	Gap56       string `json:"gap_5_6,omitempty"`
+
+	top5 float64
+}
+
+// packStats works out the pack numbers from times sorted fastest first.
+func packStats(secs []float64) PackStats {
+	p := PackStats{Runners: len(secs)}
+	if len(secs) >= scoringRunners {
+		var sum float64
+		for _, s := range secs[:scoringRunners] {
+			sum += s
+		}
+		p.top5 = sum / scoringRunners
+		p.Top5Average = formatRaceTime(p.top5)
+		p.Spread15 = formatRaceTime(secs[scoringRunners-1] - secs[0])
+	}
+	if len(secs) > scoringRunners {
+		p.Gap56 = formatRaceTime(secs[scoringRunners] - secs[scoringRunners-1])
+	}
+	if len(secs) >= teamRunners {
+		p.Spread17 = formatRaceTime(secs[teamRunners-1] - secs[0])
+	}
+	return p
+}
+
+// PackRunner is one of a team's top seven in a race.
+type PackRunner struct {
+	Position  int    `json:"position"`
+	AthleteID int    `json:"athleteId,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Time      string `json:"time"`
+	// Behind is the time back from the team's first runner.
+	Behind string `json:"behind"`
+
+	seconds float64
+}
+
+type TeamAnalytics struct {
+	Gender string `json:"gender"`
+	PackStats
+	Pack []PackRunner `json:"pack"`
+}
+
+type MeetAnalytics struct {
+	MeetID   int             `json:"meetId"`
+	Name     string          `json:"name"`
+	Date     string          `json:"date"`
+	Distance float64         `json:"distance_m,omitempty"`
+	Teams    []TeamAnalytics `json:"teams"`
+}
+
+// meetAnalyticsHandler serves GET /api/meets/{id}/analytics: pack numbers
+// for each gender's team at the meet.
+func meetAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, errBadRequest("Invalid meet ID"))
+		return
+	}
+	var ma MeetAnalytics
+	var date time.Time
+	err = db.QueryRow(ctx, "SELECT id, name, date, COALESCE(distance_m, 0) FROM meets WHERE id = $1", id).Scan(
+		&ma.MeetID, &ma.Name, &date, &ma.Distance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, r, errNotFound("Meet not found"))
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	ma.Date = date.Format("2006-01-02")
+
+	rows, err := db.Query(ctx,
+		`SELECT a.id, a.name, a.privacy, a.gender, r.time
+		 FROM results r JOIN athletes a ON a.id = r.athlete_id
+		 WHERE r.meet_id = $1 AND a.gender IN ('M', 'F')`, id)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	public := publicView(r)
+	byGender := map[string][]PackRunner{}
+	for rows.Next() {
+		var p PackRunner
+		var a Athlete
+		var gender string
+		if err := rows.Scan(&p.AthleteID, &a.Name, &a.Privacy, &gender, &p.Time); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if p.seconds, err = parseRaceTime(p.Time); err != nil {
+			continue
+		}
+		a.Gender = gender
+		if public && (!redactAthlete(&a) || a.Gender == "") {
+			p.AthleteID, a.Name = 0, ""
+		}
+		p.Name = a.Name
+		byGender[gender] = append(byGender[gender], p)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	ma.Teams = []TeamAnalytics{}
+	for _, gender := range []string{"F", "M"} {
+		runners := byGender[gender]
+		if len(runners) == 0 {
+			continue
+		}
+		slices.SortFunc(runners, func(a, b PackRunner) int {
+			if c := cmp.Compare(a.seconds, b.seconds); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Name, b.Name)
+		})
+		secs := make([]float64, len(runners))
+		for i, p := range runners {
+			secs[i] = p.seconds
+		}
+		team := TeamAnalytics{Gender: gender, PackStats: packStats(secs)}
+		for i, p := range runners[:min(teamRunners, len(runners))] {
+			p.Position = i + 1
+			p.Behind = formatDifferential(p.seconds - runners[0].seconds)
+			team.Pack = append(team.Pack, p)
+		}
+		ma.Teams = append(ma.Teams, team)
+	}
+	json.NewEncoder(w).Encode(ma)
+}
+
+// TrendPoint is one meet in a season trend, with times converted to 5k.
+type TrendPoint struct {
+	MeetID   int     `json:"meetId"`
+	Name     string  `json:"name"`
+	Date     string  `json:"date"`
+	Distance float64 `json:"distance_m,omitempty"`
+	PackStats
+}
+
+type SeasonTrend struct {
+	Gender string       `json:"gender"`
+	Meets  []TrendPoint `json:"meets"`
+	// Change compares the top-five average at the last meet where five
+	// finished with the first; negative means faster.
+	Change string `json:"change,omitempty"`
+}
+
+type SeasonAnalytics struct {
+	Year   int           `json:"year"`
+	Trends []SeasonTrend `json:"trends"`
+}
+
+// seasonTrendHandler serves GET /api/analytics/season: each gender's pack
+// numbers meet by meet for ?year= (the latest meet's year by default), and
+// ?gender=M|F for just one.
+func seasonTrendHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	q := r.URL.Query()
+	gender := q.Get("gender")
+	if gender != "" && gender != "M" && gender != "F" {
+		writeError(w, r, errBadRequest("gender must be M or F"))
+		return
+	}
+	var year int
+	if q.Has("year") {
+		var err error
+		if year, err = strconv.Atoi(q.Get("year")); err != nil {
+			writeError(w, r, errBadRequest("Invalid year"))
+			return
+		}
+	} else {
+		var latest *time.Time
+		if err := db.QueryRow(ctx, "SELECT max(date) FROM meets").Scan(&latest); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		year = time.Now().Year()
+		if latest != nil {
+			year = latest.Year()
+		}
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT m.id, m.name, m.date, COALESCE(m.distance_m, 0), a.gender, r.time
+		 FROM results r JOIN athletes a ON a.id = r.athlete_id JOIN meets m ON m.id = r.meet_id
+		 WHERE extract(year FROM m.date) = $1 AND a.gender IN ('M', 'F') AND ($2::text = '' OR a.gender = $2)
+		 ORDER BY m.date, m.id`, year, gender)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	type race struct {
+		point TrendPoint
+		secs  []float64
+	}
+	races := map[string][]*race{}
+	for rows.Next() {
+		var p TrendPoint
+		var date time.Time
+		var g, t string
+		if err := rows.Scan(&p.MeetID, &p.Name, &date, &p.Distance, &g, &t); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		sec, err := parseRaceTime(t)
+		if err != nil {
+			continue
+		}
+		list := races[g]
+		if len(list) == 0 || list[len(list)-1].point.MeetID != p.MeetID {
+			p.Date = date.Format("2006-01-02")
+			list = append(list, &race{point: p})
+			races[g] = list
+		}
+		cur := list[len(list)-1]
+		cur.secs = append(cur.secs, seasonResult{seconds: sec, distance: p.Distance}.at(lineupDistance))
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	season := SeasonAnalytics{Year: year, Trends: []SeasonTrend{}}
+	for _, g := range []string{"F", "M"} {
+		if gender != "" && g != gender {
+			continue
+		}
+		trend := SeasonTrend{Gender: g, Meets: []TrendPoint{}}
+		var first, last float64
+		for _, rc := range races[g] {
+			slices.Sort(rc.secs)
+			rc.point.PackStats = packStats(rc.secs)
+			if top5 := rc.point.top5; top5 > 0 {
+				if first == 0 {
+					first = top5
+				}
+				last = top5
+			}
+			trend.Meets = append(trend.Meets, rc.point)
+		}
+		if first > 0 {
+			trend.Change = formatDifferential(last - first)
+		}
+		season.Trends = append(season.Trends, trend)
+	}
+	json.NewEncoder(w).Encode(season)
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestPackStats(t *testing.T) {
+	tests := []struct {
+		name string
+		secs []float64
+		want PackStats
+	}{
+		{"no finishers", nil, PackStats{}},
+		{"one finisher", []float64{1000}, PackStats{Runners: 1}},
+		// Fewer than five can't score, so there's nothing to report.
+		{"four finishers", []float64{1000, 1010, 1020, 1030}, PackStats{Runners: 4}},
+		{
+			"exactly five",
+			[]float64{1000, 1010, 1020, 1030, 1040},
+			PackStats{Runners: 5, Top5Average: "17:00", Spread15: "0:40"},
+		},
+		{
+			"six adds the 5-6 gap",
+			[]float64{1000, 1010, 1020, 1030, 1040, 1052.5},
+			PackStats{Runners: 6, Top5Average: "17:00", Spread15: "0:40", Gap56: "0:12.5"},
+		},
+		{
+			"seven adds the 1-7 spread",
+			[]float64{1000, 1010, 1020, 1030, 1040, 1050, 1090},
+			PackStats{Runners: 7, Top5Average: "17:00", Spread15: "0:40", Gap56: "0:10", Spread17: "1:30"},
+		},
+		{
+			"runners past seven change nothing",
+			[]float64{1000, 1010, 1020, 1030, 1040, 1050, 1090, 1500, 1600},
+			PackStats{Runners: 9, Top5Average: "17:00", Spread15: "0:40", Gap56: "0:10", Spread17: "1:30"},
+		},
+		{
+			"ties give zero spreads",
+			[]float64{1000, 1000, 1000, 1000, 1000, 1000},
+			PackStats{Runners: 6, Top5Average: "16:40", Spread15: "0:00", Gap56: "0:00"},
+		},
+		{
+			"average rounds to tenths",
+			[]float64{1000, 1000, 1000, 1000, 1001.2},
+			PackStats{Runners: 5, Top5Average: "16:40.2", Spread15: "0:01.2"},
+		},
+	}
+	for _, tt := range tests {
+		got := packStats(tt.secs)
+		got.top5 = 0
+		if got != tt.want {
+			t.Errorf("%s: packStats = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPackStatsTop5(t *testing.T) {
+	if got := packStats([]float64{1000, 1010, 1020, 1030}).top5; got != 0 {
+		t.Errorf("top5 with four finishers = %v, want 0 so trends skip the meet", got)
+	}
+	if got := packStats([]float64{1000, 1010, 1020, 1030, 1040, 2000}).top5; got != 1020 {
+		t.Errorf("top5 = %v, want 1020", got)
+	}
+}
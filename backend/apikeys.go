@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- API keys ---
+//
+// Long-lived keys for integrations such as a timing system posting results,
+// sent in an X-API-Key header. Each key carries scopes limiting it to reading
+// or writing particular resources; reads with the matching scope get the full
+// (staff) view rather than the privacy-redacted public one. Only a SHA-256
+// hash of the key is stored, so it is shown once, at creation.
+
+const (
+	apiKeyHeader = "X-API-Key"
+	apiKeyPrefix = "xc_"
+	roleAPIKey   = "api_key"
+
+	scopeResultsRead  = "results:read"
+	scopeResultsWrite = "results:write"
+	scopeRosterRead   = "roster:read"
+
+	// last_used_at is only rewritten once a minute per key.
+	apiKeyUsageGranularity = time.Minute
+)
+
+var apiKeyScopes = []string{scopeResultsRead, scopeResultsWrite, scopeRosterRead}
+
+// resourceScopes maps each API route to the scopes that read and write it.
+// Routes not listed are closed to API keys.
+var resourceScopes = map[string]struct{ read, write string }{
+	"/api/results":       {scopeResultsRead, scopeResultsWrite},
+	"/api/result-splits": {scopeResultsRead, scopeResultsWrite},
+	"/api/athletes":      {scopeRosterRead, ""},
+}
+
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Key        string     `json:"key,omitempty"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// canRead reports whether the caller gets the full view of r's resource.
+func (p *Principal) canRead(r *http.Request) bool {
+	if p.Role == roleAPIKey {
+		s := resourceScopes[r.URL.Path].read
+		return s != "" && slices.Contains(p.Scopes, s)
+	}
+	return p.isStaff()
+}
+
+// canWrite reports whether the caller may change r's resource.
+func (p *Principal) canWrite(r *http.Request) bool {
+	if p.Role == roleAPIKey {
+		s := resourceScopes[r.URL.Path].write
+		return s != "" && slices.Contains(p.Scopes, s)
+	}
+	return p.isStaff()
+}
+
+// lookupAPIKey returns the principal for an active key, or nil.
+func lookupAPIKey(ctx context.Context, key string) (*Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil
+	}
+	var id int
+	var name string
+	var scopes []string
+	var lastUsed *time.Time
+	err := db.QueryRow(ctx,
+		"SELECT id, name, scopes, last_used_at FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL",
+		hashAPIKey(key)).Scan(&id, &name, &scopes, &lastUsed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastUsed == nil || time.Since(*lastUsed) > apiKeyUsageGranularity {
+		if _, err := db.Exec(ctx, "UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+			return nil, err
+		}
+	}
+	return &Principal{Username: "api-key:" + name, Role: roleAPIKey, Scopes: scopes}, nil
+}
+
+// apiKeysHandler creates, lists and revokes API keys. Admin only.
+func apiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(ctx,
+			`SELECT id, name, prefix, scopes, created_by, created_at, last_used_at, revoked_at
+			 FROM api_keys ORDER BY revoked_at IS NOT NULL, created_at DESC`)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		keys := []APIKey{}
+		for rows.Next() {
+			var k APIKey
+			if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			keys = append(keys, k)
+		}
+		json.NewEncoder(w).Encode(keys)
+
+	case http.MethodPost:
+		var k APIKey
+		if !decodeValid(w, r, &k) {
+			return
+		}
+		raw := make([]byte, 32)
+		rand.Read(raw)
+		k.Key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+		k.Prefix = k.Key[:len(apiKeyPrefix)+8]
+		k.CreatedBy = currentPrincipal(r).Username
+		err := db.QueryRow(ctx,
+			`INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by)
+			 VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+			k.Name, k.Prefix, hashAPIKey(k.Key), k.Scopes, k.CreatedBy).Scan(&k.ID, &k.CreatedAt)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		log.Printf("API key %q (%s) created by %q with scopes %v", k.Name, k.Prefix, k.CreatedBy, k.Scopes)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(k)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		tag, err := db.Exec(ctx,
+			"UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL", id)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeError(w, r, errNotFound("API key not found"))
+			return
+		}
+		log.Printf("API key %d revoked by %q", id, currentPrincipal(r).Username)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Practice attendance ---
+//
+// Coaches create a practice session and check athletes in against it, one
+// status per athlete. The summary turns that into an attendance percentage:
+// present sessions over the sessions that count, where excused and injured
+// days don't count either way and a session with no record for the athlete
+// counts as an absence. Staff only, apart from /api/me/attendance.
+
+const (
+	attendancePresent = "present"
+	attendanceExcused = "excused"
+	attendanceAbsent  = "absent"
+	attendanceInjured = "injured"
+)
+
+var attendanceStatuses = []string{attendancePresent, attendanceExcused, attendanceAbsent, attendanceInjured}
+
+type PracticeSession struct {
+	ID         int          `json:"id"`
+	Date       string       `json:"date"`
+	Name       string       `json:"name"`
+	Notes      string       `json:"notes,omitempty"`
+	Present    int          `json:"present"`
+	Attendance []Attendance `json:"attendance,omitempty"`
+	Version    int          `json:"version"`
+}
+
+type Attendance struct {
+	SessionID  int    `json:"sessionId,omitempty"`
+	AthleteID  int    `json:"athleteId"`
+	Status     string `json:"status"`
+	Note       string `json:"note,omitempty"`
+	RecordedBy string `json:"recorded_by,omitempty"`
+}
+
+// checkIn is the body of a bulk check-in. Rest, when set, is given to every
+// athlete on the roster not listed in Records.
+type checkIn struct {
+	Records []Attendance `json:"records"`
+	Rest    string       `json:"rest,omitempty"`
+}
+
+const sessionSelect = `SELECT s.id, s.date, s.name, COALESCE(s.notes, ''), s.version,
+	(SELECT count(*) FROM attendance a WHERE a.session_id = s.id AND a.status = 'present')
+	FROM practice_sessions s`
+
+func scanSession(row pgx.Row) (PracticeSession, error) {
+	var s PracticeSession
+	var date time.Time
+	err := row.Scan(&s.ID, &date, &s.Name, &s.Notes, &s.Version, &s.Present)
+	s.Date = date.Format("2006-01-02")
+	return s, err
+}
+
+func loadAttendance(ctx context.Context, sessionID int) ([]Attendance, error) {
+	rows, err := db.Query(ctx,
+		`SELECT a.session_id, a.athlete_id, a.status, COALESCE(a.note, ''), a.recorded_by
+		 FROM attendance a JOIN athletes ath ON ath.id = a.athlete_id
+		 WHERE a.session_id = $1 ORDER BY ath.name`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []Attendance{}
+	for rows.Next() {
+		var a Attendance
+		if err := rows.Scan(&a.SessionID, &a.AthleteID, &a.Status, &a.Note, &a.RecordedBy); err != nil {
+			return nil, err
+		}
+		records = append(records, a)
+	}
+	return records, rows.Err()
+}
+
+// practicesHandler manages practice sessions. A single session comes with
+// its attendance records. Staff only.
+func practicesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("id") {
+			id, ok := queryID(w, r)
+			if !ok {
+				return
+			}
+			s, err := scanSession(db.QueryRow(ctx, sessionSelect+" WHERE s.id = $1", id))
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, r, errNotFound("Practice not found"))
+				return
+			}
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			if s.Attendance, err = loadAttendance(ctx, id); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			setETag(w, s.Version)
+			json.NewEncoder(w).Encode(s)
+			return
+		}
+		from, to, ok := dateRange(w, r)
+		if !ok {
+			return
+		}
+		rows, err := db.Query(ctx, sessionSelect+" WHERE s.date BETWEEN $1 AND $2 ORDER BY s.date DESC, s.id DESC", from, to)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		sessions := []PracticeSession{}
+		for rows.Next() {
+			s, err := scanSession(rows)
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			sessions = append(sessions, s)
+		}
+		json.NewEncoder(w).Encode(sessions)
+
+	case http.MethodPost:
+		var s PracticeSession
+		if !decodeValid(w, r, &s) {
+			return
+		}
+		err := db.QueryRow(ctx,
+			"INSERT INTO practice_sessions (date, name, notes) VALUES ($1, $2, NULLIF($3, '')) RETURNING id, version",
+			s.Date, s.Name, s.Notes).Scan(&s.ID, &s.Version)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		s.Attendance = nil
+		setETag(w, s.Version)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(s)
+
+	case http.MethodPut:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var s PracticeSession
+		if !decodeValid(w, r, &s) {
+			return
+		}
+		tag, err := db.Exec(ctx,
+			`UPDATE practice_sessions SET date=$1, name=$2, notes=NULLIF($3, ''), version=version+1, updated_at=CURRENT_TIMESTAMP
+			 WHERE id=$4 AND ($5::int = 0 OR version=$5)`,
+			s.Date, s.Name, s.Notes, id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "practice_sessions", id, expected, "Practice not found")
+			return
+		}
+		s, err = scanSession(db.QueryRow(ctx, sessionSelect+" WHERE s.id = $1", id))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, s.Version)
+		json.NewEncoder(w).Encode(s)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		tag, err := db.Exec(ctx, "DELETE FROM practice_sessions WHERE id = $1 AND ($2::int = 0 OR version = $2)", id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "practice_sessions", id, expected, "Practice not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// checkInHandler records attendance for ?sessionId= in one go: GET lists the
+// records, PUT sets the ones given (and optionally everyone else) without
+// touching the rest. Staff only.
+func checkInHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	sessionID, err := strconv.Atoi(r.URL.Query().Get("sessionId"))
+	if err != nil {
+		writeError(w, r, errBadRequest("sessionId parameter required"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var exists bool
+		if err := db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM practice_sessions WHERE id = $1)", sessionID).Scan(&exists); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if !exists {
+			writeError(w, r, errNotFound("Practice not found"))
+			return
+		}
+		records, err := loadAttendance(ctx, sessionID)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		json.NewEncoder(w).Encode(records)
+
+	case http.MethodPut:
+		var body checkIn
+		if !decodeValid(w, r, &body) {
+			return
+		}
+		recordedBy := currentPrincipal(r).Username
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		// Lock the session so two coaches checking in at once don't interleave.
+		var exists bool
+		err = tx.QueryRow(ctx, "SELECT true FROM practice_sessions WHERE id = $1 FOR UPDATE", sessionID).Scan(&exists)
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, r, errNotFound("Practice not found"))
+			return
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+
+		const upsert = `INSERT INTO attendance (session_id, athlete_id, status, note, recorded_by)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+			ON CONFLICT (session_id, athlete_id) DO UPDATE
+			SET status = EXCLUDED.status, note = EXCLUDED.note, recorded_by = EXCLUDED.recorded_by, updated_at = CURRENT_TIMESTAMP`
+		listed := make([]int, 0, len(body.Records))
+		for _, a := range body.Records {
+			if _, err := tx.Exec(ctx, upsert, sessionID, a.AthleteID, a.Status, a.Note, recordedBy); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			listed = append(listed, a.AthleteID)
+		}
+		if body.Rest != "" {
+			_, err := tx.Exec(ctx,
+				`INSERT INTO attendance (session_id, athlete_id, status, recorded_by)
+				 SELECT $1, id, $2, $3 FROM athletes WHERE NOT (id = ANY($4))
+				 ON CONFLICT (session_id, athlete_id) DO UPDATE
+				 SET status = EXCLUDED.status, note = NULL, recorded_by = EXCLUDED.recorded_by, updated_at = CURRENT_TIMESTAMP`,
+				sessionID, body.Rest, recordedBy, listed)
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+		}
+		if _, err := tx.Exec(ctx,
+			"UPDATE practice_sessions SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1", sessionID); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+
+		records, err := loadAttendance(ctx, sessionID)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		json.NewEncoder(w).Encode(records)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// AttendanceSummary is one athlete's attendance over a date range.
+type AttendanceSummary struct {
+	AthleteID  int    `json:"athleteId"`
+	Name       string `json:"name"`
+	Sessions   int    `json:"sessions"`
+	Present    int    `json:"present"`
+	Excused    int    `json:"excused"`
+	Absent     int    `json:"absent"`
+	Injured    int    `json:"injured"`
+	Unrecorded int    `json:"unrecorded"`
+	// Percent is present over the sessions that count; nil when every
+	// session was excused or injured.
+	Percent *float64 `json:"percent"`
+}
+
+// attendanceSummaries totals attendance between from and to for every
+// athlete, or just athleteID when it isn't zero.
+func attendanceSummaries(ctx context.Context, from, to time.Time, athleteID int) ([]AttendanceSummary, error) {
+	rows, err := db.Query(ctx,
+		`SELECT ath.id, ath.name,
+		     (SELECT count(*) FROM practice_sessions WHERE date BETWEEN $1 AND $2),
+		     count(*) FILTER (WHERE a.status = 'present'),
+		     count(*) FILTER (WHERE a.status = 'excused'),
+		     count(*) FILTER (WHERE a.status = 'absent'),
+		     count(*) FILTER (WHERE a.status = 'injured')
+		 FROM athletes ath
+		 LEFT JOIN (attendance a JOIN practice_sessions s ON s.id = a.session_id AND s.date BETWEEN $1 AND $2)
+		     ON a.athlete_id = ath.id
+		 WHERE $3::int = 0 OR ath.id = $3
+		 GROUP BY ath.id, ath.name ORDER BY ath.name`,
+		from, to, athleteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []AttendanceSummary{}
+	for rows.Next() {
+		var s AttendanceSummary
+		if err := rows.Scan(&s.AthleteID, &s.Name, &s.Sessions, &s.Present, &s.Excused, &s.Absent, &s.Injured); err != nil {
+			return nil, err
+		}
+		s.Unrecorded = s.Sessions - s.Present - s.Excused - s.Absent - s.Injured
+		if counted := s.Sessions - s.Excused - s.Injured; counted > 0 {
+			pct := math.Round(float64(s.Present)/float64(counted)*1000) / 10
+			s.Percent = &pct
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// attendanceSummaryHandler reports attendance percentages over ?from= and
+// ?to=, for every athlete or just ?athleteId=. Staff only.
+func attendanceSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return
+	}
+	var athleteID int
+	if q := r.URL.Query(); q.Has("athleteId") {
+		var err error
+		if athleteID, err = strconv.Atoi(q.Get("athleteId")); err != nil {
+			writeError(w, r, errBadRequest("Invalid athleteId format"))
+			return
+		}
+	}
+	summaries, err := attendanceSummaries(ctx, from, to, athleteID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// meAttendanceHandler reports the caller's athlete's attendance.
+func meAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return
+	}
+	summaries, err := attendanceSummaries(ctx, from, to, currentPrincipal(r).AthleteID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if len(summaries) == 0 {
+		writeError(w, r, errNotFound("Athlete not found"))
+		return
+	}
+	json.NewEncoder(w).Encode(summaries[0])
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// --- Availability ---
+//
+// Each athlete has one current availability: healthy, limited or out, an
+// expected return date and notes. An athlete with no record is healthy.
+// Injury details about minors are not public, so all of this is staff-only;
+// rankings show it only in the staff view, and meet entries are staff-only.
+
+const (
+	availabilityHealthy = "healthy"
+	availabilityLimited = "limited"
+	availabilityOut     = "out"
+)
+
+var availabilityStatuses = []string{availabilityHealthy, availabilityLimited, availabilityOut}
+
+type Availability struct {
+	AthleteID  int        `json:"athleteId"`
+	Name       string     `json:"name,omitempty"`
+	Status     string     `json:"status"`
+	ReturnDate string     `json:"return_date,omitempty"`
+	Notes      string     `json:"notes,omitempty"`
+	UpdatedBy  string     `json:"updated_by,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	Version    int        `json:"version"`
+}
+
+// availableOn reports whether the athlete can race on day: anyone not out,
+// or out with an expected return on or before day.
+func (a Availability) availableOn(day time.Time) bool {
+	if a.Status != availabilityOut {
+		return true
+	}
+	ret, err := time.Parse("2006-01-02", a.ReturnDate)
+	return err == nil && !ret.After(day)
+}
+
+// Rows for athletes without a record read as healthy, version 0.
+const availabilitySelect = `SELECT ath.id, ath.name, COALESCE(av.status, 'healthy'), av.return_date,
+	COALESCE(av.notes, ''), COALESCE(av.updated_by, ''), av.updated_at, COALESCE(av.version, 0)
+	FROM athletes ath LEFT JOIN athlete_availability av ON av.athlete_id = ath.id`
+
+func scanAvailability(row pgx.Row) (Availability, error) {
+	var a Availability
+	var ret *time.Time
+	err := row.Scan(&a.AthleteID, &a.Name, &a.Status, &ret, &a.Notes, &a.UpdatedBy, &a.UpdatedAt, &a.Version)
+	if ret != nil {
+		a.ReturnDate = ret.Format("2006-01-02")
+	}
+	return a, err
+}
+
+// loadAvailability returns every athlete's availability by athlete ID.
+func loadAvailability(ctx context.Context) (map[int]Availability, error) {
+	rows, err := db.Query(ctx, availabilitySelect)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byAthlete := map[int]Availability{}
+	for rows.Next() {
+		a, err := scanAvailability(rows)
+		if err != nil {
+			return nil, err
+		}
+		byAthlete[a.AthleteID] = a
+	}
+	return byAthlete, rows.Err()
+}
+
+// availabilityHandler reads and sets availability. GET lists every athlete
+// (?status= narrows it) or one with ?athleteId=; PUT ?athleteId= replaces
+// that athlete's record. Staff only.
+func availabilityHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	q := r.URL.Query()
+	var athleteID int
+	if q.Has("athleteId") || r.Method == http.MethodPut {
+		var err error
+		if athleteID, err = strconv.Atoi(q.Get("athleteId")); err != nil {
+			writeError(w, r, errBadRequest("athleteId parameter required"))
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if athleteID != 0 {
+			a, err := scanAvailability(db.QueryRow(ctx, availabilitySelect+" WHERE ath.id = $1", athleteID))
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, r, errNotFound("Athlete not found"))
+				return
+			}
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			setETag(w, a.Version)
+			json.NewEncoder(w).Encode(a)
+			return
+		}
+		rows, err := db.Query(ctx,
+			availabilitySelect+" WHERE $1::text = '' OR COALESCE(av.status, 'healthy') = $1 ORDER BY ath.name", q.Get("status"))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		list := []Availability{}
+		for rows.Next() {
+			a, err := scanAvailability(rows)
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			list = append(list, a)
+		}
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPut:
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var a Availability
+		if !decodeValid(w, r, &a) {
+			return
+		}
+		a.AthleteID = athleteID
+		a.UpdatedBy = currentPrincipal(r).Username
+		// Without If-Match the record is created or replaced; with it, only
+		// an existing record at that version is updated.
+		var tag pgconn.CommandTag
+		var err error
+		if expected == 0 {
+			tag, err = db.Exec(ctx,
+				`INSERT INTO athlete_availability (athlete_id, status, return_date, notes, updated_by)
+				 VALUES ($1, $2, NULLIF($3, '')::date, NULLIF($4, ''), $5)
+				 ON CONFLICT (athlete_id) DO UPDATE
+				 SET status = EXCLUDED.status, return_date = EXCLUDED.return_date, notes = EXCLUDED.notes,
+				     updated_by = EXCLUDED.updated_by, version = athlete_availability.version + 1, updated_at = CURRENT_TIMESTAMP`,
+				a.AthleteID, a.Status, a.ReturnDate, a.Notes, a.UpdatedBy)
+		} else {
+			tag, err = db.Exec(ctx,
+				`UPDATE athlete_availability SET status = $2, return_date = NULLIF($3, '')::date, notes = NULLIF($4, ''),
+				 updated_by = $5, version = version + 1, updated_at = CURRENT_TIMESTAMP
+				 WHERE athlete_id = $1 AND version = $6`,
+				a.AthleteID, a.Status, a.ReturnDate, a.Notes, a.UpdatedBy, expected)
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeError(w, r, errPreconditionFailed())
+			return
+		}
+		a, err = scanAvailability(db.QueryRow(ctx, availabilitySelect+" WHERE ath.id = $1", athleteID))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		// Staff rankings show availability, so cached copies are now stale.
+		responses.invalidate()
+		setETag(w, a.Version)
+		json.NewEncoder(w).Encode(a)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Response cache ---
+//
+// Public GET responses are kept in memory until the next successful write to
+// any API resource, so game-day traffic is served without touching Postgres.
+// Entries carry an ETag and Last-Modified so browsers can revalidate cheaply
+// and get a 304 when nothing has changed.
+
+// maxCachedResponses bounds memory use; query strings are client-controlled,
+// so the cache is simply cleared when it fills up.
+const maxCachedResponses = 256
+
+type cachedResponse struct {
+	body        []byte
+	etag        string
+	contentType string
+}
+
+type responseCache struct {
+	mu           sync.RWMutex
+	entries      map[string]*cachedResponse
+	generation   uint64
+	lastModified time.Time
+}
+
+var responses = newResponseCache()
+
+func newResponseCache() *responseCache {
+	return &responseCache{
+		entries:      make(map[string]*cachedResponse),
+		lastModified: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// get returns the entry for key (nil on a miss), the time data last changed,
+// and the generation to hand back to put.
+func (c *responseCache) get(key string) (*cachedResponse, time.Time, uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.entries[key], c.lastModified, c.generation
+}
+
+// put stores an entry unless a write invalidated the cache while the response
+// was being built, in which case the body may already be stale.
+func (c *responseCache) put(key string, generation uint64, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if len(c.entries) >= maxCachedResponses {
+		c.entries = make(map[string]*cachedResponse)
+	}
+	c.entries[key] = entry
+}
+
+func (c *responseCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cachedResponse)
+	c.generation++
+	c.lastModified = time.Now().UTC().Truncate(time.Second)
+}
+
+// bufferedWriter holds a handler's response so it can be cached and given an
+// ETag before anything is sent.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) WriteHeader(status int) { b.status = status }
+
+func (b *bufferedWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
+
+// statusRecorder passes a response through while remembering its status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// cached serves GET requests from the response cache and invalidates it after
+// every successful write that passes through.
+func cached(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next(rec, r)
+			if rec.status < 400 {
+				responses.invalidate()
+			}
+			return
+		}
+
+		// Staff see data that privacy settings hide from everyone else, so the
+		// two views are cached separately.
+		view := "staff:"
+		if publicView(r) {
+			view = "public:"
+		}
+		key := view + r.URL.Path + "?" + r.URL.Query().Encode()
+		entry, modified, generation := responses.get(key)
+		if entry == nil {
+			buf := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+			next(buf, r)
+			if buf.status != http.StatusOK {
+				w.WriteHeader(buf.status)
+				w.Write(buf.body.Bytes())
+				return
+			}
+			// Single-record responses already carry their version ETag, which
+			// If-Match relies on; only lists get a content hash.
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(buf.body.Bytes())
+				etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+			}
+			entry = &cachedResponse{body: buf.body.Bytes(), etag: etag, contentType: w.Header().Get("Content-Type")}
+			responses.put(key, generation, entry)
+		}
+
+		// Any of these credentials can switch the view, and shared caches
+		// must never keep the staff one.
+		h := w.Header()
+		h.Add("Vary", "Authorization, Cookie, "+apiKeyHeader)
+		h.Set("Content-Type", entry.contentType)
+		h.Set("ETag", entry.etag)
+		h.Set("Last-Modified", modified.Format(http.TimeFormat))
+		if view == "public:" {
+			h.Set("Cache-Control", "no-cache")
+		} else {
+			h.Set("Cache-Control", "private, no-cache")
+		}
+		if notModified(r, entry.etag, modified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write(entry.body)
+	}
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since only
+// when no entity tags were sent (RFC 9110 §13.2.2).
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !modified.After(t)
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Head-to-head ---
+//
+// Compares two athletes over the meets they both ran. Gaps are from the
+// first athlete's side, like split differentials: "-0:12" means they were 12
+// seconds ahead. Best times are compared after converting each to 5k, so a
+// 3200m best and a 5k best can still be set side by side. Privacy applies as
+// for /api/results: a hidden athlete can't be compared in the public view.
+
+type Competitor struct {
+	AthleteID  int    `json:"athleteId"`
+	Name       string `json:"name"`
+	Races      int    `json:"races"`
+	Wins       int    `json:"wins"`
+	Best       string `json:"best,omitempty"`
+	BestMeetID int    `json:"bestMeetId,omitempty"`
+	Best5k     string `json:"best_5k,omitempty"`
+
+	best5k  float64
+	results map[int]Result
+}
+
+// SharedMeet is one meet both athletes ran. Times and Places follow the order
+// of ?athletes=.
+type SharedMeet struct {
+	MeetID   int       `json:"meetId"`
+	Name     string    `json:"name"`
+	Date     string    `json:"date"`
+	Times    [2]string `json:"times"`
+	Places   [2]int    `json:"places"`
+	TimeGap  string    `json:"time_gap,omitempty"`
+	PlaceGap *int      `json:"place_gap,omitempty"`
+	// WinnerID is zero for a tie or when a time can't be read.
+	WinnerID int `json:"winnerId,omitempty"`
+
+	date time.Time
+}
+
+type HeadToHead struct {
+	Athletes []Competitor `json:"athletes"`
+	Meets    []SharedMeet `json:"meets"`
+	Ties     int          `json:"ties"`
+	BestGap  string       `json:"best_gap,omitempty"`
+}
+
+// loadCompetitor reads an athlete and their results. It reports false when
+// there's no such athlete, or none the caller may see.
+func loadCompetitor(ctx context.Context, athleteID int, public bool) (Competitor, bool, error) {
+	c := Competitor{AthleteID: athleteID, results: map[int]Result{}}
+	a := Athlete{}
+	err := db.QueryRow(ctx, "SELECT name, privacy FROM athletes WHERE id = $1", athleteID).Scan(&a.Name, &a.Privacy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, err
+	}
+	if public && !redactAthlete(&a) {
+		return c, false, nil
+	}
+	c.Name = a.Name
+
+	rows, err := queryAthleteResults(ctx, athleteID, public)
+	if err != nil {
+		return c, false, err
+	}
+	defer rows.Close()
+
+	best := 0.0
+	for rows.Next() {
+		res, err := scanResult(rows, public)
+		if err != nil {
+			return c, false, err
+		}
+		c.results[res.MeetID] = res
+		c.Races++
+		sec, err := parseRaceTime(res.Time)
+		if err != nil {
+			continue
+		}
+		if c.Best == "" || sec < best {
+			best, c.Best, c.BestMeetID = sec, res.Time, res.MeetID
+		}
+		if t := (seasonResult{seconds: sec, distance: res.distance}).at(lineupDistance); c.best5k == 0 || t < c.best5k {
+			c.best5k = t
+		}
+	}
+	if c.best5k > 0 {
+		c.Best5k = formatRaceTime(c.best5k)
+	}
+	return c, true, rows.Err()
+}
+
+// compareHandler serves GET /api/compare?athletes=14,15.
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	parts := strings.Split(r.URL.Query().Get("athletes"), ",")
+	if len(parts) != 2 {
+		writeError(w, r, errBadRequest("athletes must be two athlete IDs, e.g. athletes=14,15"))
+		return
+	}
+	var ids [2]int
+	for i, p := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			writeError(w, r, errBadRequest("Invalid athlete ID "+strconv.Quote(p)))
+			return
+		}
+		ids[i] = id
+	}
+	if ids[0] == ids[1] {
+		writeError(w, r, errBadRequest("athletes must be two different athletes"))
+		return
+	}
+
+	public := publicView(r)
+	h2h := HeadToHead{Meets: []SharedMeet{}}
+	for _, id := range ids {
+		c, ok, err := loadCompetitor(ctx, id, public)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if !ok {
+			writeError(w, r, errNotFound("Athlete not found"))
+			return
+		}
+		h2h.Athletes = append(h2h.Athletes, c)
+	}
+	first, second := &h2h.Athletes[0], &h2h.Athletes[1]
+
+	var shared []int
+	for meetID := range first.results {
+		if _, ok := second.results[meetID]; ok {
+			shared = append(shared, meetID)
+		}
+	}
+	rows, err := db.Query(ctx, "SELECT id, name, date FROM meets WHERE id = ANY($1)", shared)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m SharedMeet
+		if err := rows.Scan(&m.MeetID, &m.Name, &m.date); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		m.Date = m.date.Format("2006-01-02")
+		a, b := first.results[m.MeetID], second.results[m.MeetID]
+		m.Times = [2]string{a.Time, b.Time}
+		m.Places = [2]int{a.Place, b.Place}
+		if a.Place > 0 && b.Place > 0 {
+			gap := a.Place - b.Place
+			m.PlaceGap = &gap
+		}
+		ta, errA := parseRaceTime(a.Time)
+		tb, errB := parseRaceTime(b.Time)
+		if errA == nil && errB == nil {
+			m.TimeGap = formatDifferential(ta - tb)
+			switch {
+			case ta < tb:
+				m.WinnerID = first.AthleteID
+				first.Wins++
+			case tb < ta:
+				m.WinnerID = second.AthleteID
+				second.Wins++
+			default:
+				h2h.Ties++
+			}
+		}
+		h2h.Meets = append(h2h.Meets, m)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	slices.SortFunc(h2h.Meets, func(a, b SharedMeet) int {
+		if c := a.date.Compare(b.date); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MeetID, b.MeetID)
+	})
+	if first.best5k > 0 && second.best5k > 0 {
+		h2h.BestGap = formatDifferential(first.best5k - second.best5k)
+	}
+	json.NewEncoder(w).Encode(h2h)
+}
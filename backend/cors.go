@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// --- CORS ---
+//
+// Two policies cover every route. Public reads (GET/HEAD on the team data)
+// are open to the configured public origins, "*" by default, so other sites
+// can embed results. Anything that changes data or logs in is limited to the
+// staff allowlist in cors_origins, with credentials allowed; the site itself
+// is same-origin and needs no entry.
+
+const (
+	corsAllowHeaders  = "Content-Type, Authorization, X-API-Key, X-CSRF-Token, X-Request-ID, If-Match, If-None-Match"
+	corsExposeHeaders = "X-Request-ID, ETag, Last-Modified, Retry-After"
+)
+
+type corsPolicy struct {
+	origins     []string
+	methods     string
+	credentials bool
+}
+
+func publicReadPolicy() *corsPolicy {
+	return &corsPolicy{origins: cfg.CORSPublicOrigins, methods: "GET, HEAD, OPTIONS"}
+}
+
+func staffPolicy() *corsPolicy {
+	return &corsPolicy{origins: cfg.CORSOrigins, methods: "GET, HEAD, POST, PUT, DELETE, OPTIONS", credentials: true}
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or ""
+// when the policy doesn't admit it. Credentialed policies always echo the
+// exact origin since browsers reject "*" alongside credentials.
+func (p *corsPolicy) allowOrigin(origin string) string {
+	for _, o := range p.origins {
+		if o == "*" && !p.credentials {
+			return "*"
+		}
+		if o == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// corsMiddleware applies the public policy to reads and the staff policy to
+// writes, for routes that serve both.
+func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return withCORS(publicReadPolicy, staffPolicy, next)
+}
+
+// staffCORS applies the staff policy to every method, for routes such as
+// login that have no public use.
+func staffCORS(next http.HandlerFunc) http.HandlerFunc {
+	return withCORS(staffPolicy, staffPolicy, next)
+}
+
+func withCORS(read, write func() *corsPolicy, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+
+		// A preflight is judged by the method it asks about, not OPTIONS.
+		method := r.Method
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			method = r.Header.Get("Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		policy := write()
+		if method == http.MethodGet || method == http.MethodHead {
+			policy = read()
+		}
+
+		allowed := ""
+		if origin != "" {
+			allowed = policy.allowOrigin(origin)
+		}
+		if allowed != "" {
+			h.Set("Access-Control-Allow-Origin", allowed)
+			h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+			if policy.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method == http.MethodOptions {
+			if preflight && allowed != "" {
+				h.Set("Access-Control-Allow-Methods", policy.methods)
+				h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.CORSMaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Browsers still send simple cross-site writes, they just hide the
+		// response. Refuse them outright unless the origin is allowed.
+		isRead := method == http.MethodGet || method == http.MethodHead
+		if !isRead && origin != "" && allowed == "" && !sameOrigin(r, origin) {
+			writeError(w, r, errForbidden("Origin not allowed"))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// sameOrigin reports whether origin names the host the request was sent to.
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Meet entries and lineups ---
+//
+// An entry puts an athlete in an upcoming meet's race, either in the lineup
+// or as an alternate. A Varsity lineup takes at most the selection rules'
+// varsity_size runners of each gender and JV has no cap; alternates don't
+// count against it. Athletes are
+// seeded by the mean of their recentRaces latest results from the year
+// before the meet, each converted to 5k with Riegel's formula.
+//
+// An athlete who is out on the meet date can't be newly entered, and is left
+// out of suggested lineups; one entered before getting hurt stays on the list,
+// flagged. Staff only, since the flags carry availability.
+
+const (
+	lineupDistance      = 5000.0
+	recentRaces         = 3
+	suggestedAlternates = 2
+)
+
+type MeetEntry struct {
+	ID           int    `json:"id,omitempty"`
+	AthleteID    int    `json:"athleteId"`
+	Name         string `json:"name,omitempty"`
+	Gender       string `json:"gender,omitempty"`
+	Grade        int    `json:"grade,omitempty"`
+	Alternate    bool   `json:"alternate"`
+	Seed         string `json:"seed,omitempty"`
+	Races        int    `json:"races"`
+	Availability string `json:"availability,omitempty"`
+	ReturnDate   string `json:"return_date,omitempty"`
+	Available    bool   `json:"available"`
+
+	seconds float64
+}
+
+// Lineup is a future meet with its entries, saved or suggested.
+type Lineup struct {
+	FutureMeetID int         `json:"futureMeetId"`
+	Meet         string      `json:"meet"`
+	Date         string      `json:"date"`
+	Level        string      `json:"level"`
+	Limit        int         `json:"limit,omitempty"`
+	Entries      []MeetEntry `json:"entries"`
+
+	day     time.Time
+	version int
+}
+
+// meetLineup is the body of a lineup PUT.
+type meetLineup struct {
+	Entries []MeetEntry `json:"entries"`
+}
+
+func scanLineup(row pgx.Row) (Lineup, error) {
+	var l Lineup
+	err := row.Scan(&l.FutureMeetID, &l.Meet, &l.day, &l.Level, &l.version)
+	l.Date = l.day.Format("2006-01-02")
+	return l, err
+}
+
+// lineupLimit is the most lineup runners per gender at level: the varsity
+// size from the selection rules for Varsity, and no limit (0) for JV.
+func lineupLimit(ctx context.Context, level string) (int, error) {
+	if level != "Varsity" {
+		return 0, nil
+	}
+	rules, err := loadSelectionRules(ctx)
+	return rules.VarsitySize, err
+}
+
+const lineupSelect = "SELECT id, name, date, level, version FROM future_meets"
+
+// loadMarks returns each athlete's results from the year up to day, newest
+// first, converted to lineupDistance.
+func loadMarks(ctx context.Context, day time.Time) (map[int][]float64, error) {
+	rows, err := db.Query(ctx,
+		`SELECT r.athlete_id, r.time, COALESCE(m.distance_m, 0)
+		 FROM results r JOIN meets m ON m.id = r.meet_id
+		 WHERE m.date <= $1::date AND m.date > $1::date - interval '1 year'
+		 ORDER BY m.date DESC, m.id DESC`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	marks := map[int][]float64{}
+	for rows.Next() {
+		var athleteID int
+		var t string
+		var s seasonResult
+		if err := rows.Scan(&athleteID, &t, &s.distance); err != nil {
+			return nil, err
+		}
+		if s.seconds, err = parseRaceTime(t); err != nil || s.seconds <= 0 {
+			continue
+		}
+		marks[athleteID] = append(marks[athleteID], s.at(lineupDistance))
+	}
+	return marks, rows.Err()
+}
+
+// recentMark is the mean of the n newest marks, or +Inf with none.
+func recentMark(marks []float64, n int) float64 {
+	n = min(n, len(marks))
+	if n == 0 {
+		return math.Inf(1)
+	}
+	var sum float64
+	for _, m := range marks[:n] {
+		sum += m
+	}
+	return sum / float64(n)
+}
+
+// seedEntries fills in each entry's seed and availability on day, then sorts
+// them by gender, lineup before alternates, then seed.
+func seedEntries(ctx context.Context, day time.Time, entries []MeetEntry) error {
+	marks, err := loadMarks(ctx, day)
+	if err != nil {
+		return err
+	}
+	availability, err := loadAvailability(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		e := &entries[i]
+		e.Races = min(len(marks[e.AthleteID]), recentRaces)
+		e.seconds = recentMark(marks[e.AthleteID], recentRaces)
+		if e.Races > 0 {
+			e.Seed = formatRaceTime(e.seconds)
+		}
+		av, ok := availability[e.AthleteID]
+		if !ok {
+			av.Status = availabilityHealthy
+		}
+		e.Availability, e.ReturnDate, e.Available = av.Status, av.ReturnDate, av.availableOn(day)
+	}
+	slices.SortFunc(entries, func(a, b MeetEntry) int {
+		if c := cmp.Compare(a.Gender, b.Gender); c != 0 {
+			return c
+		}
+		if a.Alternate != b.Alternate {
+			if a.Alternate {
+				return 1
+			}
+			return -1
+		}
+		if c := cmp.Compare(a.seconds, b.seconds); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return nil
+}
+
+func loadEntries(ctx context.Context, l *Lineup) error {
+	rows, err := db.Query(ctx,
+		`SELECT e.id, e.athlete_id, a.name, COALESCE(a.gender, ''), a.grade, e.alternate
+		 FROM meet_entries e JOIN athletes a ON a.id = e.athlete_id
+		 WHERE e.future_meet_id = $1`, l.FutureMeetID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	l.Entries = []MeetEntry{}
+	for rows.Next() {
+		var e MeetEntry
+		if err := rows.Scan(&e.ID, &e.AthleteID, &e.Name, &e.Gender, &e.Grade, &e.Alternate); err != nil {
+			return err
+		}
+		l.Entries = append(l.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return seedEntries(ctx, l.day, l.Entries)
+}
+
+// lineupFor reads the future meet named by ?meetId=, writing a 400 or 404 when
+// there isn't one.
+func lineupFor(ctx context.Context, w http.ResponseWriter, r *http.Request) (Lineup, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get("meetId"))
+	if err != nil {
+		writeError(w, r, errBadRequest("meetId parameter required"))
+		return Lineup{}, false
+	}
+	l, err := scanLineup(db.QueryRow(ctx, lineupSelect+" WHERE id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, r, errNotFound("Future meet not found"))
+		return Lineup{}, false
+	}
+	if err == nil {
+		l.Limit, err = lineupLimit(ctx, l.Level)
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return Lineup{}, false
+	}
+	return l, true
+}
+
+// meetEntriesHandler reads and replaces the entries for ?meetId= (a future
+// meet). PUT takes the whole list, so an empty one clears it, and bumps the
+// meet's version so its ETag works in If-Match. Staff only.
+func meetEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		l, ok := lineupFor(ctx, w, r)
+		if !ok {
+			return
+		}
+		if err := loadEntries(ctx, &l); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, l.version)
+		json.NewEncoder(w).Encode(l)
+
+	case http.MethodPut:
+		meetID, err := strconv.Atoi(r.URL.Query().Get("meetId"))
+		if err != nil {
+			writeError(w, r, errBadRequest("meetId parameter required"))
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var body meetLineup
+		if !decodeValid(w, r, &body) {
+			return
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		l, err := scanLineup(tx.QueryRow(ctx,
+			`UPDATE future_meets SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+			 WHERE id = $1 AND ($2::int = 0 OR version = $2) RETURNING id, name, date, level, version`,
+			meetID, expected))
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeMissedWrite(w, r, "future_meets", meetID, expected, "Future meet not found")
+			return
+		}
+		if err == nil {
+			l.Limit, err = lineupLimit(ctx, l.Level)
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if errs, err := checkLineup(ctx, tx, l, body.Entries); err != nil {
+			writeDBError(w, r, err)
+			return
+		} else if len(errs) > 0 {
+			writeError(w, r, errValidation(errs))
+			return
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM meet_entries WHERE future_meet_id = $1", meetID); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		for _, e := range body.Entries {
+			if _, err := tx.Exec(ctx,
+				"INSERT INTO meet_entries (future_meet_id, athlete_id, alternate) VALUES ($1, $2, $3)",
+				meetID, e.AthleteID, e.Alternate); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		// The meet's version is part of the public future-meets list.
+		responses.invalidate()
+
+		if err := loadEntries(ctx, &l); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, l.version)
+		json.NewEncoder(w).Encode(l)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// checkLineup enforces what needs the database: the level's lineup limit per
+// gender, and no new entries for athletes who are out on the meet date.
+func checkLineup(ctx context.Context, tx pgx.Tx, l Lineup, entries []MeetEntry) (ValidationErrors, error) {
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.AthleteID
+	}
+	genders := map[int]string{}
+	rows, err := tx.Query(ctx, "SELECT id, COALESCE(gender, '') FROM athletes WHERE id = ANY($1)", ids)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		var gender string
+		if err := rows.Scan(&id, &gender); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		genders[id] = gender
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	entered := map[int]bool{}
+	rows, err = tx.Query(ctx, "SELECT athlete_id FROM meet_entries WHERE future_meet_id = $1", l.FutureMeetID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entered[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	availability, err := loadAvailability(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs ValidationErrors
+	perGender := map[string]int{}
+	for i, e := range entries {
+		gender, ok := genders[e.AthleteID]
+		if !ok {
+			errs.add(fmt.Sprintf("entries[%d].athleteId", i), "does not exist")
+			continue
+		}
+		if av := availability[e.AthleteID]; !entered[e.AthleteID] && !av.availableOn(l.day) {
+			errs.add(fmt.Sprintf("entries[%d].athleteId", i), "is out on %s", l.Date)
+		}
+		if !e.Alternate {
+			perGender[gender]++
+		}
+	}
+	if l.Limit > 0 {
+		for _, n := range perGender {
+			if n > l.Limit {
+				errs.add("entries", "a %s lineup has at most %d runners per gender", l.Level, l.Limit)
+				break
+			}
+		}
+	}
+	return errs, nil
+}
+
+// suggestLineupHandler proposes entries for ?meetId=: every athlete available
+// on the meet date, fastest seed first, up to the level's limit per gender
+// with the next suggestedAlternates as alternates. Nothing is saved; PUT the
+// entries to /api/meet-entries to use them. Staff only.
+func suggestLineupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	l, ok := lineupFor(ctx, w, r)
+	if !ok {
+		return
+	}
+	rows, err := db.Query(ctx, "SELECT id, name, COALESCE(gender, ''), grade FROM athletes")
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	var candidates []MeetEntry
+	for rows.Next() {
+		var e MeetEntry
+		if err := rows.Scan(&e.AthleteID, &e.Name, &e.Gender, &e.Grade); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		candidates = append(candidates, e)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if err := seedEntries(ctx, l.day, candidates); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	l.Entries = []MeetEntry{}
+	taken := map[string]int{}
+	for _, e := range candidates {
+		if !e.Available {
+			continue
+		}
+		n := taken[e.Gender]
+		switch {
+		case l.Limit == 0 || n < l.Limit:
+		case n < l.Limit+suggestedAlternates:
+			e.Alternate = true
+		default:
+			continue
+		}
+		taken[e.Gender]++
+		l.Entries = append(l.Entries, e)
+	}
+	json.NewEncoder(w).Encode(l)
+}
+
+// entryExportHandler downloads the entries for ?meetId= as CSV for the meet
+// host. Availability stays out of it. Staff only.
+func entryExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	l, ok := lineupFor(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := loadEntries(ctx, &l); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="entries-%d.csv"`, l.FutureMeetID))
+	out := csv.NewWriter(w)
+	out.Write([]string{"Meet", "Date", "Level", "Athlete", "Gender", "Grade", "Entry", "Seed (5k)"})
+	for _, e := range l.Entries {
+		entry := "Lineup"
+		if e.Alternate {
+			entry = "Alternate"
+		}
+		out.Write(csvRow(l.Meet, l.Date, l.Level, e.Name, e.Gender, strconv.Itoa(e.Grade), entry, e.Seed))
+	}
+	out.Flush()
+}
+
+// csvRow quotes cells a spreadsheet would read as a formula, such as a name
+// starting with "=", by prefixing them with an apostrophe.
+func csvRow(cells ...string) []string {
+	for i, c := range cells {
+		if c != "" && strings.ContainsRune("=+-@\t\r", rune(c[0])) {
+			cells[i] = "'" + c
+		}
+	}
+	return cells
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// --- Errors ---
+
+// Machine-readable error codes returned in the "code" field of every error
+// response.
+const (
+	codeBadRequest       = "bad_request"
+	codeValidationFailed = "validation_failed"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeConflict         = "conflict"
+	codeRateLimited      = "rate_limited"
+	codePrecondition     = "precondition_failed"
+	codeInternal         = "internal_error"
+	codeTimeout          = "timeout"
+)
+
+// APIError is the single error shape returned by every endpoint:
+//
+//	{"error": "Athlete not found", "code": "not_found", "request_id": "…"}
+//
+// "error" stays a human-readable string so existing clients that display it
+// keep working; "fields" is only present for validation failures.
+type APIError struct {
+	Status    int              `json:"-"`
+	Message   string           `json:"error"`
+	Code      string           `json:"code"`
+	RequestID string           `json:"request_id,omitempty"`
+	Fields    ValidationErrors `json:"fields,omitempty"`
+}
+
+func (e *APIError) Error() string { return e.Message }
+
+func errBadRequest(msg string) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: codeBadRequest, Message: msg}
+}
+
+func errValidation(fields ValidationErrors) *APIError {
+	return &APIError{Status: http.StatusUnprocessableEntity, Code: codeValidationFailed, Message: "Validation failed", Fields: fields}
+}
+
+func errUnauthorized(msg string) *APIError {
+	return &APIError{Status: http.StatusUnauthorized, Code: codeUnauthorized, Message: msg}
+}
+
+func errForbidden(msg string) *APIError {
+	return &APIError{Status: http.StatusForbidden, Code: codeForbidden, Message: msg}
+}
+
+func errNotFound(msg string) *APIError {
+	return &APIError{Status: http.StatusNotFound, Code: codeNotFound, Message: msg}
+}
+
+func errMethodNotAllowed() *APIError {
+	return &APIError{Status: http.StatusMethodNotAllowed, Code: codeMethodNotAllowed, Message: "Method not allowed"}
+}
+
+func errConflict(msg string) *APIError {
+	return &APIError{Status: http.StatusConflict, Code: codeConflict, Message: msg}
+}
+
+func errPreconditionFailed() *APIError {
+	return &APIError{Status: http.StatusPreconditionFailed, Code: codePrecondition, Message: "Record was modified by someone else; reload and try again"}
+}
+
+func errTimeout() *APIError {
+	return &APIError{Status: http.StatusServiceUnavailable, Code: codeTimeout, Message: "The database took too long to respond; try again"}
+}
+
+func errInternal() *APIError {
+	return &APIError{Status: http.StatusInternalServerError, Code: codeInternal, Message: "Internal server error"}
+}
+
+// writeError writes e as JSON, stamping it with the request ID.
+func writeError(w http.ResponseWriter, r *http.Request, e *APIError) {
+	out := *e
+	out.RequestID = requestID(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(out.Status)
+	json.NewEncoder(w).Encode(out)
+}
+
+// Postgres SQLSTATE codes we translate into client errors.
+const (
+	pgNotNullViolation    = "23502"
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+	pgCheckViolation      = "23514"
+)
+
+// constraintMessages gives friendlier wording for constraints clients are
+// likely to trip over. Constraints not listed fall back to a generic message.
+var constraintMessages = map[string]string{
+	"results_athlete_id_meet_id_key":         "This athlete already has a result for this meet",
+	"results_athlete_id_fkey":                "Athlete does not exist",
+	"results_meet_id_fkey":                   "Meet does not exist",
+	"users_username_key":                     "Username is already taken",
+	"users_athlete_id_fkey":                  "Athlete does not exist",
+	"athlete_notes_athlete_id_fkey":          "Athlete does not exist",
+	"athlete_goals_athlete_id_fkey":          "Athlete does not exist",
+	"athlete_goals_target_meet_id_fkey":      "Future meet does not exist",
+	"workouts_athlete_id_fkey":               "Athlete does not exist",
+	"workout_plans_group_id_fkey":            "Training group does not exist",
+	"training_groups_name_key":               "A training group with this name already exists",
+	"training_group_members_athlete_id_fkey": "Athlete does not exist",
+	"attendance_athlete_id_fkey":             "Athlete does not exist",
+	"athlete_availability_athlete_id_fkey":   "Athlete does not exist",
+	"meet_entries_athlete_id_fkey":           "Athlete does not exist",
+	"selection_overrides_athlete_id_fkey":    "Athlete does not exist",
+	"idx_users_email":                        "Email is already in use",
+}
+
+// dbError maps a database error to an APIError. Constraint violations become
+// 409/422 responses; anything else is logged and reported as a 500 without
+// leaking SQL details to the client.
+func dbError(r *http.Request, err error) *APIError {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errNotFound("Not found")
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("request %s: database timeout: %v", requestID(r.Context()), err)
+		return errTimeout()
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		msg, ok := constraintMessages[pgErr.ConstraintName]
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			if !ok {
+				msg = "Record already exists"
+			}
+			return errConflict(msg)
+		case pgForeignKeyViolation:
+			if !ok {
+				msg = "Referenced record does not exist"
+			}
+			return &APIError{Status: http.StatusUnprocessableEntity, Code: codeValidationFailed, Message: msg}
+		case pgCheckViolation, pgNotNullViolation:
+			if !ok {
+				msg = "Value violates a data constraint"
+			}
+			e := &APIError{Status: http.StatusUnprocessableEntity, Code: codeValidationFailed, Message: msg}
+			if pgErr.ColumnName != "" {
+				e.Fields = ValidationErrors{{Field: pgErr.ColumnName, Message: msg}}
+			}
+			return e
+		}
+	}
+
+	log.Printf("request %s: database error: %v", requestID(r.Context()), err)
+	return errInternal()
+}
+
+func writeDBError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, dbError(r, err))
+}
+
+// --- Request IDs ---
+
+type requestIDKey struct{}
+
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts short IDs made of characters that are safe to echo
+// into headers and log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDMiddleware tags each request with an ID, reusing a reasonable
+// X-Request-ID from a proxy when present, and echoes it in the response so a
+// user's error report can be matched to the server log.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			b := make([]byte, 8)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// --- Optimistic concurrency ---
+//
+// Every entity row carries a version that is bumped on each update. GET and
+// write responses expose it as a strong ETag; PUT and DELETE accept it back in
+// If-Match so two coaches editing the same record can't silently overwrite
+// each other. Requests without If-Match (or with "*") write unconditionally.
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
+// ifMatchVersion returns the version the client expects to be replacing, or 0
+// when the request carries no precondition. An If-Match that can't be one of
+// our ETags can never match, so it gets a 412 straight away.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return 0, true
+	}
+	if len(h) < 2 || h[0] != '"' || h[len(h)-1] != '"' {
+		writeError(w, r, errPreconditionFailed())
+		return 0, false
+	}
+	version, err := strconv.Atoi(h[1 : len(h)-1])
+	if err != nil || version <= 0 {
+		writeError(w, r, errPreconditionFailed())
+		return 0, false
+	}
+	return version, true
+}
+
+// writeMissedWrite reports why a version-guarded UPDATE or DELETE touched no
+// rows: the record is gone (404) or someone else changed it first (412).
+func writeMissedWrite(w http.ResponseWriter, r *http.Request, table string, id, expected int, notFound string) {
+	if expected != 0 {
+		ctx, cancel := queryContext(r)
+		defer cancel()
+		var exists bool
+		err := db.QueryRow(ctx,
+			"SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = $1)", id).Scan(&exists)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if exists {
+			writeError(w, r, errPreconditionFailed())
+			return
+		}
+	}
+	writeError(w, r, errNotFound(notFound))
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Goals and predictions ---
+//
+// Coaches set goal times for an athlete, usually tied to a future meet such
+// as region or state. Progress compares each goal with the athlete's season
+// best, and the prediction projects an end-of-season time from the season's
+// results: each is first converted to the goal distance with Riegel's formula
+// (results from meets with no distance are taken as already at it), then a
+// least-squares line through time against date is extended to the target
+// date. The projection is held within predictionLimit of the season best so
+// one bad race early on can't run away with it.
+//
+// The season is the calendar year of the athlete's most recent result.
+
+const (
+	defaultGoalDistance = 5000.0
+	predictionLimit     = 0.05
+)
+
+type Goal struct {
+	ID           int       `json:"id"`
+	AthleteID    int       `json:"athleteId"`
+	Label        string    `json:"label"`
+	TargetTime   string    `json:"target_time"`
+	Distance     float64   `json:"distance_m"`
+	TargetMeetID *int      `json:"targetMeetId,omitempty"`
+	TargetMeet   string    `json:"target_meet,omitempty"`
+	TargetDate   string    `json:"target_date,omitempty"`
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+const goalSelect = `SELECT g.id, g.athlete_id, g.label, g.target_time, g.distance_m, g.target_meet_id,
+	COALESCE(f.name, ''), f.date, g.version, g.created_at
+	FROM athlete_goals g LEFT JOIN future_meets f ON f.id = g.target_meet_id`
+
+func scanGoal(row pgx.Row) (Goal, error) {
+	var g Goal
+	var date *time.Time
+	err := row.Scan(&g.ID, &g.AthleteID, &g.Label, &g.TargetTime, &g.Distance, &g.TargetMeetID,
+		&g.TargetMeet, &date, &g.Version, &g.CreatedAt)
+	if date != nil {
+		g.TargetDate = date.Format("2006-01-02")
+	}
+	return g, err
+}
+
+func loadGoals(ctx context.Context, athleteID int) ([]Goal, error) {
+	rows, err := db.Query(ctx, goalSelect+" WHERE g.athlete_id = $1 ORDER BY f.date NULLS LAST, g.id", athleteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []Goal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+// goalsHandler manages goals. Staff only.
+func goalsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("id") {
+			id, ok := queryID(w, r)
+			if !ok {
+				return
+			}
+			g, err := scanGoal(db.QueryRow(ctx, goalSelect+" WHERE g.id = $1", id))
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, r, errNotFound("Goal not found"))
+				return
+			}
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			setETag(w, g.Version)
+			json.NewEncoder(w).Encode(g)
+			return
+		}
+		athleteID, err := strconv.Atoi(r.URL.Query().Get("athleteId"))
+		if err != nil {
+			writeError(w, r, errBadRequest("athleteId parameter required"))
+			return
+		}
+		goals, err := loadGoals(ctx, athleteID)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		json.NewEncoder(w).Encode(goals)
+
+	case http.MethodPost:
+		var g Goal
+		if !decodeValid(w, r, &g) {
+			return
+		}
+		if g.Distance == 0 {
+			g.Distance = defaultGoalDistance
+		}
+		var id int
+		err := db.QueryRow(ctx,
+			`INSERT INTO athlete_goals (athlete_id, label, target_time, distance_m, target_meet_id)
+			 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			g.AthleteID, g.Label, g.TargetTime, g.Distance, g.TargetMeetID).Scan(&id)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		g, err = scanGoal(db.QueryRow(ctx, goalSelect+" WHERE g.id = $1", id))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, g.Version)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(g)
+
+	case http.MethodPut:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var g Goal
+		if !decodeValid(w, r, &g) {
+			return
+		}
+		if g.Distance == 0 {
+			g.Distance = defaultGoalDistance
+		}
+		tag, err := db.Exec(ctx,
+			`UPDATE athlete_goals SET label=$1, target_time=$2, distance_m=$3, target_meet_id=$4,
+			 version=version+1, updated_at=CURRENT_TIMESTAMP
+			 WHERE id=$5 AND ($6::int = 0 OR version=$6)`,
+			g.Label, g.TargetTime, g.Distance, g.TargetMeetID, id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "athlete_goals", id, expected, "Goal not found")
+			return
+		}
+		g, err = scanGoal(db.QueryRow(ctx, goalSelect+" WHERE g.id = $1", id))
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, g.Version)
+		json.NewEncoder(w).Encode(g)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		tag, err := db.Exec(ctx, "DELETE FROM athlete_goals WHERE id = $1 AND ($2::int = 0 OR version = $2)", id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "athlete_goals", id, expected, "Goal not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// seasonResult is one race in an athlete's current season.
+type seasonResult struct {
+	meetID   int
+	date     time.Time
+	seconds  float64
+	distance float64
+}
+
+// at returns the result converted to meters; unknown distances are taken as
+// already being at it.
+func (s seasonResult) at(meters float64) float64 {
+	if s.distance <= 0 {
+		return s.seconds
+	}
+	return riegel(s.seconds, s.distance, meters)
+}
+
+// loadSeason returns the athlete's results from the calendar year of their
+// latest result, oldest first.
+func loadSeason(ctx context.Context, athleteID int) ([]seasonResult, error) {
+	rows, err := db.Query(ctx,
+		`SELECT m.id, m.date, r.time, COALESCE(m.distance_m, 0)
+		 FROM results r JOIN meets m ON m.id = r.meet_id
+		 WHERE r.athlete_id = $1 AND extract(year FROM m.date) = (
+		     SELECT max(extract(year FROM m2.date)) FROM results r2 JOIN meets m2 ON m2.id = r2.meet_id WHERE r2.athlete_id = $1)
+		 ORDER BY m.date, m.id`, athleteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var season []seasonResult
+	for rows.Next() {
+		var s seasonResult
+		var t string
+		if err := rows.Scan(&s.meetID, &s.date, &t, &s.distance); err != nil {
+			return nil, err
+		}
+		if s.seconds, err = parseRaceTime(t); err != nil || s.seconds <= 0 {
+			continue
+		}
+		season = append(season, s)
+	}
+	return season, rows.Err()
+}
+
+// seasonEnd is the date of the last scheduled meet in the season, or the last
+// race when nothing is scheduled.
+func seasonEnd(ctx context.Context, season []seasonResult) (time.Time, error) {
+	last := season[len(season)-1].date
+	var end *time.Time
+	err := db.QueryRow(ctx,
+		"SELECT max(date) FROM future_meets WHERE extract(year FROM date) = $1", last.Year()).Scan(&end)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end == nil || end.Before(last) {
+		return last, nil
+	}
+	return *end, nil
+}
+
+// seasonBest returns the fastest season time converted to meters and the
+// meet it was run at.
+func seasonBest(season []seasonResult, meters float64) (float64, int) {
+	best, meetID := math.Inf(1), 0
+	for _, s := range season {
+		if t := s.at(meters); t < best {
+			best, meetID = t, s.meetID
+		}
+	}
+	return best, meetID
+}
+
+type Prediction struct {
+	AthleteID    int     `json:"athleteId"`
+	Distance     float64 `json:"distance_m"`
+	TargetDate   string  `json:"target_date"`
+	Predicted    string  `json:"predicted"`
+	SeasonBest   string  `json:"season_best"`
+	Races        int     `json:"races"`
+	TrendPerWeek string  `json:"trend_per_week,omitempty"`
+	Method       string  `json:"method"`
+}
+
+// predict projects a time at meters on target from season, which must not be
+// empty. With a single race, or every race on one day, there is no trend
+// and the season best stands.
+func predict(season []seasonResult, meters float64, target time.Time) Prediction {
+	best, _ := seasonBest(season, meters)
+	p := Prediction{
+		Distance:   meters,
+		TargetDate: target.Format("2006-01-02"),
+		SeasonBest: formatRaceTime(best),
+		Predicted:  formatRaceTime(best),
+		Races:      len(season),
+		Method:     "season-best",
+	}
+
+	// Least squares of seconds against days since the first race.
+	first := season[0].date
+	var n, sx, sy, sxx, sxy float64
+	for _, s := range season {
+		x := s.date.Sub(first).Hours() / 24
+		y := s.at(meters)
+		n++
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	denom := n*sxx - sx*sx
+	if n < 2 || denom == 0 {
+		return p
+	}
+	slope := (n*sxy - sx*sy) / denom
+	intercept := (sy - slope*sx) / n
+	x := target.Sub(first).Hours() / 24
+	projected := intercept + slope*x
+	projected = min(max(projected, best*(1-predictionLimit)), best*(1+predictionLimit))
+
+	p.Predicted = formatRaceTime(projected)
+	p.TrendPerWeek = formatDifferential(slope * 7)
+	p.Method = "linear-trend"
+	return p
+}
+
+// predictionHandler projects an athlete's time at ?distance= (5k by default)
+// on ?date=, which defaults to the end of the season. Staff only.
+func predictionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	q := r.URL.Query()
+	athleteID, err := strconv.Atoi(q.Get("athleteId"))
+	if err != nil {
+		writeError(w, r, errBadRequest("athleteId parameter required"))
+		return
+	}
+	meters := defaultGoalDistance
+	if q.Has("distance") {
+		if meters, err = parseDistance(q.Get("distance")); err != nil {
+			writeError(w, r, errBadRequest("Invalid distance"))
+			return
+		}
+	}
+	var target time.Time
+	if q.Has("date") {
+		if target, err = time.Parse("2006-01-02", q.Get("date")); err != nil {
+			writeError(w, r, errBadRequest("date must be in YYYY-MM-DD format"))
+			return
+		}
+	}
+
+	season, err := loadSeason(ctx, athleteID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if len(season) == 0 {
+		writeError(w, r, errNotFound("No results to predict from"))
+		return
+	}
+	if target.IsZero() {
+		if target, err = seasonEnd(ctx, season); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+	p := predict(season, meters, target)
+	p.AthleteID = athleteID
+	json.NewEncoder(w).Encode(p)
+}
+
+// GoalProgress is a goal set against the season so far. Times are at the
+// goal's distance.
+type GoalProgress struct {
+	Goal
+	SeasonBest       string `json:"season_best,omitempty"`
+	SeasonBestMeetID int    `json:"season_best_meetId,omitempty"`
+	// Gap is the season best minus the target: what is still to drop when
+	// positive, how far under the goal when negative.
+	Gap       string `json:"gap,omitempty"`
+	Met       bool   `json:"met"`
+	Predicted string `json:"predicted,omitempty"`
+	OnTrack   bool   `json:"on_track"`
+}
+
+type progressReport struct {
+	AthleteID int            `json:"athleteId"`
+	Races     int            `json:"races"`
+	Goals     []GoalProgress `json:"goals"`
+}
+
+// writeProgress reports on each of athleteID's goals.
+func writeProgress(w http.ResponseWriter, r *http.Request, athleteID int) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	goals, err := loadGoals(ctx, athleteID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	season, err := loadSeason(ctx, athleteID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	var end time.Time
+	if len(season) > 0 {
+		if end, err = seasonEnd(ctx, season); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+
+	report := progressReport{AthleteID: athleteID, Races: len(season), Goals: []GoalProgress{}}
+	for _, g := range goals {
+		gp := GoalProgress{Goal: g}
+		target, err := parseRaceTime(g.TargetTime)
+		if len(season) > 0 && err == nil {
+			best, meetID := seasonBest(season, g.Distance)
+			gp.SeasonBest = formatRaceTime(best)
+			gp.SeasonBestMeetID = meetID
+			gp.Gap = formatDifferential(best - target)
+			gp.Met = best <= target
+
+			on := end
+			if d, err := time.Parse("2006-01-02", g.TargetDate); err == nil {
+				on = d
+			}
+			p := predict(season, g.Distance, on)
+			gp.Predicted = p.Predicted
+			predicted, _ := parseRaceTime(p.Predicted)
+			gp.OnTrack = predicted <= target
+		}
+		report.Goals = append(report.Goals, gp)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// goalProgressHandler reports on an athlete's goals. Staff only.
+func goalProgressHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	athleteID, err := strconv.Atoi(r.URL.Query().Get("athleteId"))
+	if err != nil {
+		writeError(w, r, errBadRequest("athleteId parameter required"))
+		return
+	}
+	writeProgress(w, r, athleteID)
+}
+
+// meGoalsHandler reports on the caller's athlete's goals.
+func meGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	writeProgress(w, r, currentPrincipal(r).AthleteID)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// --- Tokens (RFC 7519 JWT) ---
+//
+// Tokens are compact JWS signed with HS256 or, when an Ed25519 key is
+// configured, EdDSA. Every token names its signing key in the "kid" header,
+// so a new key can be made active while tokens signed by the old one keep
+// verifying until they expire and the old key is removed from config.
+
+const (
+	tokenIssuer = "jones-county-xc"
+	roleAdmin   = "admin"
+
+	// MFA tokens prove the password step of a two-step login and are only
+	// accepted by /api/login/2fa; they live long enough to type in a code.
+	mfaTokenTTL    = 5 * time.Minute
+	purposeMFA     = "mfa"
+	tokenClockSkew = 30 * time.Second
+)
+
+var b64 = base64.RawURLEncoding
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Role      string `json:"role,omitempty"`
+	AthleteID int    `json:"athlete_id,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+	Purpose   string `json:"purpose,omitempty"`
+	CSRF      string `json:"csrf,omitempty"`
+}
+
+type signingKey struct {
+	id      string
+	alg     string
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func (k *signingKey) sign(input []byte) []byte {
+	if k.alg == "EdDSA" {
+		return ed25519.Sign(k.private, input)
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write(input)
+	return mac.Sum(nil)
+}
+
+func (k *signingKey) verify(input, sig []byte) bool {
+	if k.alg == "EdDSA" {
+		return ed25519.Verify(k.public, input, sig)
+	}
+	return hmac.Equal(sig, k.sign(input))
+}
+
+type keyring struct {
+	active *signingKey
+	byID   map[string]*signingKey
+}
+
+var tokenKeys *keyring
+
+// newKeyring builds the signing keys from config. Without any jwt_keys the
+// admin secret signs HS256 tokens under the "default" key ID.
+func newKeyring(c *Config) (*keyring, error) {
+	keys := c.JWTKeys
+	if len(keys) == 0 {
+		keys = []JWTKeyConfig{{ID: "default", Secret: c.AdminSecret}}
+	}
+
+	kr := &keyring{byID: make(map[string]*signingKey)}
+	for _, kc := range keys {
+		if kc.ID == "" {
+			return nil, errors.New("jwt key: id is required")
+		}
+		if _, dup := kr.byID[kc.ID]; dup {
+			return nil, fmt.Errorf("jwt key %q: duplicate id", kc.ID)
+		}
+		k := &signingKey{id: kc.ID}
+		switch {
+		case kc.Ed25519Seed != "" && kc.Secret != "":
+			return nil, fmt.Errorf("jwt key %q: set either secret or ed25519_seed, not both", kc.ID)
+		case kc.Ed25519Seed != "":
+			seed, err := base64.StdEncoding.DecodeString(kc.Ed25519Seed)
+			if err != nil || len(seed) != ed25519.SeedSize {
+				return nil, fmt.Errorf("jwt key %q: ed25519_seed must be %d base64-encoded bytes", kc.ID, ed25519.SeedSize)
+			}
+			k.alg = "EdDSA"
+			k.private = ed25519.NewKeyFromSeed(seed)
+			k.public = k.private.Public().(ed25519.PublicKey)
+		case kc.Secret != "":
+			k.alg = "HS256"
+			k.secret = []byte(kc.Secret)
+		default:
+			return nil, fmt.Errorf("jwt key %q: secret or ed25519_seed is required", kc.ID)
+		}
+		kr.byID[k.id] = k
+	}
+
+	activeID := c.JWTActiveKey
+	if activeID == "" {
+		activeID = keys[0].ID
+	}
+	kr.active = kr.byID[activeID]
+	if kr.active == nil {
+		return nil, fmt.Errorf("jwt_active_key %q is not among jwt_keys", activeID)
+	}
+	return kr, nil
+}
+
+func newTokenID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (kr *keyring) sign(claims Claims) string {
+	header, _ := json.Marshal(jwtHeader{Alg: kr.active.alg, Typ: "JWT", Kid: kr.active.id})
+	payload, _ := json.Marshal(claims)
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	return input + "." + b64.EncodeToString(kr.active.sign([]byte(input)))
+}
+
+// parse verifies a token's signature, issuer and lifetime. The algorithm must
+// match the key named by kid, so a token can't pick a weaker algorithm.
+func (kr *keyring) parse(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	rawHeader, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	var h jwtHeader
+	if err := json.Unmarshal(rawHeader, &h); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	key, ok := kr.byID[h.Kid]
+	if !ok || h.Alg != key.alg {
+		return nil, errors.New("unknown signing key")
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil || !key.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, errors.New("invalid signature")
+	}
+
+	rawClaims, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	var c Claims
+	if err := json.Unmarshal(rawClaims, &c); err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	if c.Issuer != tokenIssuer {
+		return nil, errors.New("wrong issuer")
+	}
+	if now.Add(-tokenClockSkew).Unix() > c.ExpiresAt {
+		return nil, errors.New("token expired")
+	}
+	if c.IssuedAt > now.Add(tokenClockSkew).Unix() {
+		return nil, errors.New("token issued in the future")
+	}
+	return &c, nil
+}
+
+func accessClaims(username string, acct account) Claims {
+	now := time.Now()
+	return Claims{
+		Issuer:    tokenIssuer,
+		Subject:   username,
+		Role:      acct.Role,
+		AthleteID: acct.AthleteID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(cfg.TokenTTL).Unix(),
+		ID:        newTokenID(),
+	}
+}
+
+// issueToken returns a full access token for username. Athlete and parent
+// tokens carry the athlete they are scoped to.
+func issueToken(username string, acct account) string {
+	return tokenKeys.sign(accessClaims(username, acct))
+}
+
+func issueMFAToken(username string, acct account) string {
+	now := time.Now()
+	return tokenKeys.sign(Claims{
+		Issuer:    tokenIssuer,
+		Subject:   username,
+		Role:      acct.Role,
+		AthleteID: acct.AthleteID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(mfaTokenTTL).Unix(),
+		ID:        newTokenID(),
+		Purpose:   purposeMFA,
+	})
+}
+
+// validateMFAToken returns the username and account from a pending MFA token.
+func validateMFAToken(token string) (string, account, bool) {
+	c, err := tokenKeys.parse(token, time.Now())
+	if err != nil || c.Purpose != purposeMFA {
+		return "", account{}, false
+	}
+	return c.Subject, account{Role: c.Role, AthleteID: c.AthleteID}, true
+}
+
+// --- Principal ---
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Username  string
+	Role      string
+	AthleteID int      // set for athlete and parent accounts
+	Scopes    []string // set for API keys
+	TokenID   string
+}
+
+// isStaff reports whether the caller is a coach or admin, who may read and
+// change all team data.
+func (p *Principal) isStaff() bool {
+	return p.Role == roleAdmin || p.Role == roleCoach
+}
+
+type principalKey struct{}
+
+// currentPrincipal returns the caller, or nil for anonymous requests.
+func currentPrincipal(r *http.Request) *Principal {
+	p, _ := r.Context().Value(principalKey{}).(*Principal)
+	return p
+}
+
+// credentialPaths take credentials in the body and never act on an existing
+// session, so authMiddleware ignores the session cookie there. Otherwise a
+// browser still holding an old cookie would be refused for lacking its CSRF
+// token while trying to sign in again.
+var credentialPaths = map[string]bool{
+	"/api/login":                  true,
+	"/api/login/2fa":              true,
+	"/api/password-reset":         true,
+	"/api/password-reset/confirm": true,
+}
+
+// authMiddleware verifies the caller's access token, from an Authorization:
+// Bearer header or else the session cookie, or failing both an X-API-Key,
+// and attaches the principal to the request context. Invalid or revoked
+// tokens leave the request anonymous; routes that need a caller reject it.
+// Cookie-authenticated writes must also carry the session's CSRF token,
+// since the browser sends the cookie on its own.
+func authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, fromCookie := requestToken(r)
+		if fromCookie && credentialPaths[r.URL.Path] {
+			token = ""
+		}
+		if token == "" {
+			if key := r.Header.Get(apiKeyHeader); key != "" {
+				ctx, cancel := queryContext(r)
+				p, err := lookupAPIKey(ctx, key)
+				cancel()
+				if err != nil {
+					writeDBError(w, r, err)
+					return
+				}
+				if p != nil {
+					r = r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c, err := tokenKeys.parse(token, time.Now()); err == nil && c.Purpose == "" {
+			if fromCookie && !safeMethod(r.Method) && !validCSRF(r, c) {
+				writeError(w, r, errForbidden("Missing or invalid CSRF token"))
+				return
+			}
+			ctx, cancel := queryContext(r)
+			revoked, err := sessionRevoked(ctx, c)
+			cancel()
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			if !revoked {
+				p := &Principal{Username: c.Subject, Role: c.Role, AthleteID: c.AthleteID, TokenID: c.ID}
+				r = r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestToken returns the bearer token, falling back to the session cookie.
+func requestToken(r *http.Request) (token string, fromCookie bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token, false
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value, true
+	}
+	return "", false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testSecret = "0123456789abcdef0123456789abcdef"
+	testSeed   = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=" // bytes 0..31
+)
+
+func testKeyring(t *testing.T, active string) *keyring {
+	t.Helper()
+	kr, err := newKeyring(&Config{
+		JWTKeys: []JWTKeyConfig{
+			{ID: "hs", Secret: testSecret},
+			{ID: "ed", Ed25519Seed: testSeed},
+		},
+		JWTActiveKey: active,
+	})
+	if err != nil {
+		t.Fatalf("newKeyring: %v", err)
+	}
+	return kr
+}
+
+// forge builds a token with any header, signed with HS256 under secret.
+func forge(h jwtHeader, c Claims, secret string) string {
+	header, _ := json.Marshal(h)
+	payload, _ := json.Marshal(c)
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + b64.EncodeToString(mac.Sum(nil))
+}
+
+func TestKeyringParse(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	claims := func(iat, exp time.Time) Claims {
+		return Claims{Issuer: tokenIssuer, Subject: "coach", Role: "coach", IssuedAt: iat.Unix(), ExpiresAt: exp.Unix(), ID: "t1"}
+	}
+	valid := claims(now.Add(-time.Minute), now.Add(time.Hour))
+	hs, ed := testKeyring(t, "hs"), testKeyring(t, "ed")
+	edPublic := string(ed.byID["ed"].public)
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"HS256", hs.sign(valid), true},
+		// Signed while "ed" was active; it stays in the ring, so it verifies.
+		{"EdDSA from before a rotation", ed.sign(valid), true},
+		{"expired within clock skew", hs.sign(claims(now.Add(-time.Hour), now.Add(-tokenClockSkew+time.Second))), true},
+		{"expired", hs.sign(claims(now.Add(-time.Hour), now.Add(-tokenClockSkew-time.Second))), false},
+		{"issued in the future", hs.sign(claims(now.Add(tokenClockSkew+time.Second), now.Add(time.Hour))), false},
+		{"wrong issuer", hs.sign(Claims{Issuer: "someone-else", Subject: "coach", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}), false},
+		{"unknown kid", forge(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: "retired"}, valid, testSecret), false},
+		{"missing kid", forge(jwtHeader{Alg: "HS256", Typ: "JWT"}, valid, testSecret), false},
+		{"alg none", forge(jwtHeader{Alg: "none", Typ: "JWT", Kid: "hs"}, valid, testSecret), false},
+		// The classic confusion attack: HMAC over the Ed25519 public key.
+		{"HS256 under the EdDSA kid", forge(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: "ed"}, valid, edPublic), false},
+		{"EdDSA under the HS256 kid", forge(jwtHeader{Alg: "EdDSA", Typ: "JWT", Kid: "hs"}, valid, testSecret), false},
+		{"wrong secret", forge(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: "hs"}, valid, "not the secret at all, not at all"), false},
+		{"two parts", strings.Join(strings.Split(hs.sign(valid), ".")[:2], "."), false},
+		{"empty", "", false},
+		{"garbage header", "!!!." + strings.SplitN(hs.sign(valid), ".", 2)[1], false},
+	}
+	for _, tt := range tests {
+		c, err := hs.parse(tt.token, now)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s: parse error = %v, want ok = %v", tt.name, err, tt.ok)
+			continue
+		}
+		if tt.ok && c.Subject != "coach" {
+			t.Errorf("%s: subject = %q", tt.name, c.Subject)
+		}
+	}
+}
+
+func TestKeyringParseTampered(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	kr := testKeyring(t, "ed")
+	token := kr.sign(Claims{Issuer: tokenIssuer, Subject: "athlete", Role: "athlete", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	parts := strings.Split(token, ".")
+	payload, _ := json.Marshal(Claims{Issuer: tokenIssuer, Subject: "athlete", Role: roleAdmin, IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	parts[1] = b64.EncodeToString(payload)
+	if _, err := kr.parse(strings.Join(parts, "."), now); err == nil {
+		t.Error("parse accepted a token whose claims were changed after signing")
+	}
+}
+
+func TestNewKeyringRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing id", Config{JWTKeys: []JWTKeyConfig{{Secret: testSecret}}}},
+		{"duplicate id", Config{JWTKeys: []JWTKeyConfig{{ID: "a", Secret: testSecret}, {ID: "a", Secret: testSecret}}}},
+		{"both secret and seed", Config{JWTKeys: []JWTKeyConfig{{ID: "a", Secret: testSecret, Ed25519Seed: testSeed}}}},
+		{"neither secret nor seed", Config{JWTKeys: []JWTKeyConfig{{ID: "a"}}}},
+		{"short seed", Config{JWTKeys: []JWTKeyConfig{{ID: "a", Ed25519Seed: "AAEC"}}}},
+		{"unknown active key", Config{JWTKeys: []JWTKeyConfig{{ID: "a", Secret: testSecret}}, JWTActiveKey: "b"}},
+	}
+	for _, tt := range tests {
+		if _, err := newKeyring(&tt.cfg); err == nil {
+			t.Errorf("%s: newKeyring succeeded", tt.name)
+		}
+	}
+}
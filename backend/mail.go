@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --- Outgoing email ---
+//
+// Mail goes through the mailer interface so development doesn't need an SMTP
+// server: without smtp_addr, messages are written to mail_dir as .eml files,
+// or to the log when that isn't set either.
+
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type mailer interface {
+	Send(ctx context.Context, msg Email) error
+}
+
+var mailSender mailer
+
+func newMailer(c *Config) mailer {
+	if c.SMTPAddr != "" {
+		return &smtpMailer{addr: c.SMTPAddr, username: c.SMTPUsername, password: c.SMTPPassword,
+			from: c.mailFrom.String(), envelope: c.mailFrom.Address}
+	}
+	return &fileMailer{dir: c.MailDir, from: c.mailFrom.String()}
+}
+
+// formatEmail renders msg as an RFC 5322 message.
+func formatEmail(from string, msg Email) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// smtpMailer delivers through a relay, authenticating with PLAIN when a
+// username is configured. It upgrades to STARTTLS when the server offers it,
+// and net/smtp refuses PLAIN auth over an unencrypted remote connection.
+// from is the From: header; envelope is the bare address for MAIL FROM.
+type smtpMailer struct {
+	addr, username, password, from, envelope string
+}
+
+// Send talks to the relay on the calling goroutine. The connection carries
+// ctx's deadline and is closed if ctx is cancelled, so a stuck relay can't
+// hold anything past the caller's timeout.
+func (m *smtpMailer) Send(ctx context.Context, msg Email) error {
+	host, _, err := net.SplitHostPort(m.addr)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return m.sendError(ctx, err)
+	}
+	defer c.Close()
+	if err := m.deliver(c, host, msg); err != nil {
+		return m.sendError(ctx, err)
+	}
+	return nil
+}
+
+// deliver runs the SMTP conversation after the greeting.
+func (m *smtpMailer) deliver(c *smtp.Client, host string, msg Email) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.envelope); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(formatEmail(m.from, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// sendError reports ctx's error when it is why the conversation broke off.
+func (m *smtpMailer) sendError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("smtp: %w", err)
+}
+
+// fileMailer is the development sink.
+type fileMailer struct {
+	dir, from string
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Email) error {
+	if m.dir == "" {
+		log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), newTokenID()[:8])
+	return os.WriteFile(filepath.Join(m.dir, name), formatEmail(m.from, msg), 0o600)
+}
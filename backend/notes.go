@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Coach notes and athlete self-service ---
+//
+// Coach notes are private: staff manage them through /api/athlete-notes and
+// the only other people who see them are the athlete's own athlete and parent
+// accounts, through /api/me. Nothing here goes through the public cache.
+
+type AthleteNote struct {
+	ID        int       `json:"id"`
+	AthleteID int       `json:"athleteId"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+const noteColumns = "id, athlete_id, author, body, version, created_at"
+
+func scanNote(row pgx.Row) (AthleteNote, error) {
+	var n AthleteNote
+	err := row.Scan(&n.ID, &n.AthleteID, &n.Author, &n.Body, &n.Version, &n.CreatedAt)
+	return n, err
+}
+
+// writeNotes lists the notes for athleteID, newest first.
+func writeNotes(w http.ResponseWriter, r *http.Request, athleteID int) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	rows, err := db.Query(ctx,
+		"SELECT "+noteColumns+" FROM athlete_notes WHERE athlete_id = $1 ORDER BY created_at DESC, id DESC", athleteID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	notes := []AthleteNote{}
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		notes = append(notes, n)
+	}
+	json.NewEncoder(w).Encode(notes)
+}
+
+// athleteNotesHandler manages coach notes. Staff only.
+func athleteNotesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("id") {
+			id, ok := queryID(w, r)
+			if !ok {
+				return
+			}
+			n, err := scanNote(db.QueryRow(ctx, "SELECT "+noteColumns+" FROM athlete_notes WHERE id = $1", id))
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, r, errNotFound("Note not found"))
+				return
+			}
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			setETag(w, n.Version)
+			json.NewEncoder(w).Encode(n)
+			return
+		}
+		athleteID, err := strconv.Atoi(r.URL.Query().Get("athleteId"))
+		if err != nil {
+			writeError(w, r, errBadRequest("athleteId parameter required"))
+			return
+		}
+		writeNotes(w, r, athleteID)
+
+	case http.MethodPost:
+		var n AthleteNote
+		if !decodeValid(w, r, &n) {
+			return
+		}
+		n.Author = currentPrincipal(r).Username
+		err := db.QueryRow(ctx,
+			"INSERT INTO athlete_notes (athlete_id, author, body) VALUES ($1, $2, $3) RETURNING id, version, created_at",
+			n.AthleteID, n.Author, n.Body).Scan(&n.ID, &n.Version, &n.CreatedAt)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, n.Version)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(n)
+
+	case http.MethodPut:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var n AthleteNote
+		if !decodeValid(w, r, &n) {
+			return
+		}
+		n, err := scanNote(db.QueryRow(ctx,
+			`UPDATE athlete_notes SET body = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+			 WHERE id = $2 AND ($3::int = 0 OR version = $3) RETURNING `+noteColumns,
+			n.Body, id, expected))
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeMissedWrite(w, r, "athlete_notes", id, expected, "Note not found")
+			return
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, n.Version)
+		json.NewEncoder(w).Encode(n)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		tag, err := db.Exec(ctx, "DELETE FROM athlete_notes WHERE id = $1 AND ($2::int = 0 OR version = $2)", id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "athlete_notes", id, expected, "Note not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// requireAthleteAccount limits a route to athlete and parent accounts. It
+// runs after requireAuth.
+func requireAthleteAccount(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if currentPrincipal(r).AthleteID == 0 {
+			writeError(w, r, errForbidden("Only athlete and parent accounts have this view"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// meHandler returns the signed-in account and, for athlete and parent
+// accounts, the athlete's full profile.
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	p := currentPrincipal(r)
+	resp := map[string]any{"username": p.Username, "role": p.Role}
+	if p.AthleteID != 0 {
+		var a Athlete
+		err := db.QueryRow(ctx,
+			`SELECT id, name, COALESCE(gender, ''), grade, COALESCE(personal_record, ''), COALESCE(events, ''), version
+			 FROM athletes WHERE id = $1`, p.AthleteID).Scan(&a.ID, &a.Name, &a.Gender, &a.Grade, &a.PersonalRecord, &a.Events, &a.Version)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		resp["athlete"] = a
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// meNotesHandler lists the coach notes on the caller's athlete.
+func meNotesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	writeNotes(w, r, currentPrincipal(r).AthleteID)
+}
+
+// meResultsHandler lists the caller's athlete's results with their splits.
+// The account belongs to the athlete, so privacy settings don't apply.
+func meResultsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	athleteID := currentPrincipal(r).AthleteID
+	splits, err := loadSplits(ctx, "r.athlete_id = $1", athleteID)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	rows, err := queryAthleteResults(ctx, athleteID, false)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		res, err := scanResult(rows, false)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		res.Splits = splits[res.ID]
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(results)
+}
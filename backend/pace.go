@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// --- Pace and distance conversion ---
+//
+// Meets record their race distance, which is what turns a finish time into a
+// pace. The converter estimates an equivalent time at another distance with
+// Riegel's formula, T2 = T1 × (D2 / D1)^1.06. It knows nothing about terrain,
+// so a track-to-cross-country estimate is a starting point for a coach, not a
+// prediction.
+
+const (
+	metersPerMile  = 1609.344
+	riegelExponent = 1.06
+
+	minDistance = 100.0
+	maxDistance = 100000.0
+)
+
+// formatRaceTime renders seconds as M:SS or H:MM:SS, keeping tenths when the
+// time has them.
+func formatRaceTime(sec float64) string {
+	tenths := int64(math.Round(math.Abs(sec) * 10))
+	s := tenths / 10 % 60
+	m := tenths / 600 % 60
+	h := tenths / 36000
+	var out string
+	if h > 0 {
+		out = fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	} else {
+		out = fmt.Sprintf("%d:%02d", m, s)
+	}
+	if t := tenths % 10; t != 0 {
+		out += fmt.Sprintf(".%d", t)
+	}
+	return out
+}
+
+// paces returns the per-mile and per-km pace of time over meters, or empty
+// strings when either is unknown.
+func paces(time string, meters float64) (perMile, perKm string) {
+	sec, err := parseRaceTime(time)
+	if err != nil || sec <= 0 || meters <= 0 {
+		return "", ""
+	}
+	return formatRaceTime(sec / (meters / metersPerMile)), formatRaceTime(sec / (meters / 1000))
+}
+
+// riegel estimates the time for toMeters from a time at fromMeters.
+func riegel(sec, fromMeters, toMeters float64) float64 {
+	return sec * math.Pow(toMeters/fromMeters, riegelExponent)
+}
+
+// parseDistance reads a distance such as "3200", "3200m", "5k", "5km", "2mi"
+// or "mile" into meters. A bare number is meters.
+func parseDistance(s string) (float64, error) {
+	orig := s
+	s = strings.ToLower(strings.TrimSpace(s))
+	unit := 1.0
+	for _, u := range []struct {
+		suffix string
+		meters float64
+	}{
+		{"miles", metersPerMile}, {"mile", metersPerMile}, {"mi", metersPerMile},
+		{"km", 1000}, {"k", 1000}, {"m", 1},
+	} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.meters
+			break
+		}
+	}
+	n := 1.0
+	if s != "" {
+		var err error
+		if n, err = strconv.ParseFloat(s, 64); err != nil {
+			return 0, fmt.Errorf("invalid distance %q", orig)
+		}
+	} else if unit == 1 {
+		return 0, fmt.Errorf("invalid distance %q", orig)
+	}
+	meters := n * unit
+	if meters < minDistance || meters > maxDistance || math.IsNaN(meters) {
+		return 0, fmt.Errorf("distance %q out of range", orig)
+	}
+	return meters, nil
+}
+
+type conversion struct {
+	Time        string  `json:"time"`
+	From        float64 `json:"from_m"`
+	To          float64 `json:"to_m"`
+	Estimate    string  `json:"estimate"`
+	PacePerMile string  `json:"pace_per_mile"`
+	PacePerKm   string  `json:"pace_per_km"`
+	Formula     string  `json:"formula"`
+	Exponent    float64 `json:"exponent"`
+}
+
+// convertHandler estimates an equivalent performance at another distance:
+// GET /api/tools/convert?time=10:05&from=3200m&to=5k.
+func convertHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+
+	q := r.URL.Query()
+	var errs ValidationErrors
+	sec, err := parseRaceTime(q.Get("time"))
+	if err != nil || sec <= 0 {
+		errs.add("time", "must be a time in MM:SS or H:MM:SS format")
+	}
+	from, err := parseDistance(q.Get("from"))
+	if err != nil {
+		errs.add("from", "must be a distance such as 3200m, 5k or 2mi")
+	}
+	to, err := parseDistance(q.Get("to"))
+	if err != nil {
+		errs.add("to", "must be a distance such as 3200m, 5k or 2mi")
+	}
+	if len(errs) > 0 {
+		writeError(w, r, errValidation(errs))
+		return
+	}
+
+	estimate := formatRaceTime(riegel(sec, from, to))
+	perMile, perKm := paces(estimate, to)
+	json.NewEncoder(w).Encode(conversion{
+		Time:        formatRaceTime(sec),
+		From:        from,
+		To:          to,
+		Estimate:    estimate,
+		PacePerMile: perMile,
+		PacePerKm:   perKm,
+		Formula:     "riegel",
+		Exponent:    riegelExponent,
+	})
+}
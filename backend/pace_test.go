@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatRaceTime(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0:00"},
+		{59, "0:59"},
+		{1065, "17:45"},
+		{1065.3, "17:45.3"},
+		// Rounded to tenths, carrying into the seconds and minutes.
+		{1065.34, "17:45.3"},
+		{1065.35, "17:45.4"},
+		{59.96, "1:00"},
+		{3599.99, "1:00:00"},
+		{3730, "1:02:10"},
+		// Differentials pass a negative time; the sign is the caller's.
+		{-12, "0:12"},
+	}
+	for _, tt := range tests {
+		if got := formatRaceTime(tt.in); got != tt.want {
+			t.Errorf("formatRaceTime(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRaceTimeRoundTrip(t *testing.T) {
+	for _, in := range []string{"0:59", "17:45", "17:45.3", "1:02:10", "1:00:00.5"} {
+		sec, err := parseRaceTime(in)
+		if err != nil {
+			t.Fatalf("parseRaceTime(%q): %v", in, err)
+		}
+		if got := formatRaceTime(sec); got != in {
+			t.Errorf("formatRaceTime(parseRaceTime(%q)) = %q", in, got)
+		}
+	}
+}
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"5000", 5000},
+		{"5000m", 5000},
+		{"3200 m", 3200},
+		{"5k", 5000},
+		{"5K", 5000},
+		{"5km", 5000},
+		{"2.5k", 2500},
+		{"k", 1000},
+		{"mile", metersPerMile},
+		{"2 miles", 2 * metersPerMile},
+		{"3mi", 3 * metersPerMile},
+		{"100", minDistance},
+		{"100k", maxDistance},
+	}
+	for _, tt := range tests {
+		got, err := parseDistance(tt.in)
+		if err != nil {
+			t.Errorf("parseDistance(%q) error: %v", tt.in, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseDistance(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDistanceRejects(t *testing.T) {
+	for _, in := range []string{"", "m", "fast", "5 furlongs", "99", "100.1k", "-5k", "0", "NaN", "inf", "infk", "NaNm"} {
+		if got, err := parseDistance(in); err == nil {
+			t.Errorf("parseDistance(%q) = %v, want an error", in, got)
+		}
+	}
+}
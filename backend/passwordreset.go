@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Password reset ---
+//
+// POST /api/password-reset emails a one-time link to the account with that
+// address; the response is the same whether or not one exists. The link's
+// token is stored only as a SHA-256 hash, expires after password_reset_ttl,
+// and is consumed by POST /api/password-reset/confirm, which sets the new
+// password and revokes every session issued before it.
+
+const mailSendTimeout = 30 * time.Second
+
+type passwordResetRequest struct {
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// resetLink builds the frontend URL that carries token. It comes only from
+// public_url: a link built from the request's Host header would let anyone
+// send a victim a reset link pointing at a host they control.
+func resetLink(token string) string {
+	return strings.TrimRight(cfg.PublicURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+}
+
+func passwordResetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	// Without public_url there's nowhere safe to point the link.
+	if cfg.PublicURL == "" {
+		writeError(w, r, &APIError{Status: http.StatusServiceUnavailable, Code: codeInternal, Message: "Password reset is not configured"})
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	var req passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errBadRequest("Invalid request body"))
+		return
+	}
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		writeError(w, r, errValidation(ValidationErrors{{Field: "email", Message: "is required"}}))
+		return
+	}
+
+	// Every request counts against the limiter, so the endpoint can't be
+	// used to flood an inbox or enumerate addresses.
+	ip := clientIP(r)
+	limitKeys := []string{"reset-ip:" + ip, "reset-email:" + strings.ToLower(email)}
+	now := time.Now()
+	if wait := logins.retryAfter(now, limitKeys...); wait > 0 {
+		writeRateLimited(w, r, tooManyResets, wait)
+		return
+	}
+	logins.fail(now, limitKeys...)
+
+	accepted := map[string]string{"message": "If an account uses that address, a reset link is on its way"}
+
+	var userID int
+	var username, to string
+	err := db.QueryRow(ctx, "SELECT id, username, email FROM users WHERE lower(email) = lower($1)", email).
+		Scan(&userID, &username, &to)
+	if errors.Is(err, pgx.ErrNoRows) {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(accepted)
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	raw := make([]byte, 32)
+	rand.Read(raw)
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	_, err = db.Exec(ctx,
+		"INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		userID, hashResetToken(token), now.Add(cfg.PasswordResetTTL))
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	msg := Email{
+		To:      to,
+		Subject: "Reset your Jones County XC password",
+		Body: "Someone asked to reset the password for " + username + ".\n\n" +
+			"Choose a new password here (the link works once and expires in " + cfg.PasswordResetTTL.String() + "):\n\n" +
+			resetLink(token) + "\n\n" +
+			"If this wasn't you, ignore this email and your password stays the same.\n",
+	}
+	// Send in the background: a slow relay would otherwise make existing
+	// addresses answer measurably slower than unknown ones.
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := mailSender.Send(sendCtx, msg); err != nil {
+			log.Printf("password reset mail for %q failed: %v", username, err)
+		}
+	}()
+	log.Printf("password reset requested for %q from %s", username, ip)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(accepted)
+}
+
+func passwordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	var req passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errBadRequest("Invalid request body"))
+		return
+	}
+	var errs ValidationErrors
+	errs.required("token", req.Token)
+	errs.password("password", req.Password)
+	if len(errs) > 0 {
+		writeError(w, r, errValidation(errs))
+		return
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		writeError(w, r, errInternal())
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx,
+		`UPDATE password_resets SET used_at = CURRENT_TIMESTAMP
+		 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		 RETURNING user_id`,
+		hashResetToken(req.Token)).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, r, errValidation(ValidationErrors{{Field: "token", Message: "is invalid or has expired"}}))
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	var username string
+	err = tx.QueryRow(ctx,
+		`UPDATE users SET password_hash = $2, tokens_valid_after = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 RETURNING username`,
+		userID, hash).Scan(&username)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	// Any other links still in flight are now pointless.
+	if _, err := tx.Exec(ctx, "DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	logins.reset("user:" + strings.ToLower(username))
+	log.Printf("password reset completed for %q from %s; existing sessions revoked", username, clientIP(r))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Athlete privacy ---
+//
+// Most athletes are minors, so each has a privacy setting for the public API:
+// "full" shows their profile, "initials" shows only initials with no gender,
+// grade, PR or events, and "hidden" leaves them out of athlete lists and
+// strips their identity from results. Initials next to a grade and gender
+// are enough to pick out a student, so they go together. Staff always see
+// everything. Handlers apply it after querying; the response cache
+// keeps the public and staff views apart.
+
+const (
+	privacyFull     = "full"
+	privacyInitials = "initials"
+	privacyHidden   = "hidden"
+)
+
+// publicView reports whether r gets the redacted view: anonymous callers,
+// athlete and parent accounts, and API keys without the resource's read scope.
+func publicView(r *http.Request) bool {
+	p := currentPrincipal(r)
+	return p == nil || !p.canRead(r)
+}
+
+// initials turns "Mary Jane Smith" into "M. J. S.".
+func initials(name string) string {
+	var parts []string
+	for _, word := range strings.Fields(name) {
+		first, _ := utf8.DecodeRuneInString(word)
+		parts = append(parts, string(unicode.ToUpper(first))+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// redactAthlete applies a's privacy setting for the public and reports
+// whether it may be shown at all. A full profile is shown as is; that is
+// what the setting opts into.
+func redactAthlete(a *Athlete) bool {
+	privacy := a.Privacy
+	a.Privacy = ""
+	switch privacy {
+	case privacyHidden:
+		return false
+	case privacyInitials:
+		a.Name = initials(a.Name)
+		a.Gender, a.Grade, a.PersonalRecord, a.Events = "", 0, "", ""
+	}
+	return true
+}
+
+// redactResult detaches a hidden athlete from a public result. The result
+// itself stays so meet places still add up.
+func redactResult(res *Result, privacy string) {
+	if privacy == privacyHidden {
+		res.AthleteID = 0
+	}
+}
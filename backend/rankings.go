@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// --- Rankings ---
+//
+// Each athlete's season best, ranked within their gender. Times are stored
+// as text, so the best is picked here rather than in SQL. Privacy applies as
+// it does to /api/athletes: hidden athletes are left out of the public view.
+// Initials-only athletes have no public gender, so being in a boys' or
+// girls' list would give it away; instead they're ranked in a list of their
+// own after the gendered ones, and left out when ?gender= asks for one.
+// Staff also see each athlete's availability and can leave out anyone who is
+// out today.
+
+type Ranking struct {
+	Rank        int     `json:"rank"`
+	AthleteID   int     `json:"athleteId"`
+	Name        string  `json:"name"`
+	Gender      string  `json:"gender,omitempty"`
+	Grade       int     `json:"grade,omitempty"`
+	ResultID    int     `json:"resultId"`
+	Time        string  `json:"time"`
+	MeetID      int     `json:"meetId"`
+	MeetName    string  `json:"meet_name"`
+	Distance    float64 `json:"distance_m,omitempty"`
+	PacePerMile string  `json:"pace_per_mile,omitempty"`
+	PacePerKm   string  `json:"pace_per_km,omitempty"`
+	// Staff view only.
+	Availability string `json:"availability,omitempty"`
+	ReturnDate   string `json:"return_date,omitempty"`
+
+	seconds float64
+}
+
+// rankingsHandler lists season bests. ?gender=M|F narrows to one gender and
+// ?distance= (e.g. 5k) to meets run at that distance. ?available=true, for
+// staff, drops athletes who are out today.
+func rankingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	q := r.URL.Query()
+	gender := q.Get("gender")
+	if gender != "" && gender != "M" && gender != "F" {
+		writeError(w, r, errBadRequest("gender must be M or F"))
+		return
+	}
+	var distance float64
+	if q.Has("distance") {
+		var err error
+		if distance, err = parseDistance(q.Get("distance")); err != nil {
+			writeError(w, r, errBadRequest("Invalid distance"))
+			return
+		}
+	}
+
+	public := publicView(r)
+	onlyAvailable := q.Get("available") == "true"
+	if onlyAvailable && public {
+		writeError(w, r, errForbidden("The available filter is staff-only"))
+		return
+	}
+	var availability map[int]Availability
+	if !public {
+		var err error
+		if availability, err = loadAvailability(ctx); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT a.id, a.name, COALESCE(a.gender, ''), a.grade, a.privacy,
+		        r.id, r.time, m.id, m.name, COALESCE(m.distance_m, 0)
+		 FROM results r JOIN athletes a ON a.id = r.athlete_id JOIN meets m ON m.id = r.meet_id
+		 WHERE ($1::text = '' OR a.gender = $1) AND ($2::float8 = 0 OR abs(m.distance_m - $2) < 1)`,
+		gender, distance)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	today := time.Now()
+	best := map[int]*Ranking{}
+	for rows.Next() {
+		var rk Ranking
+		var privacy string
+		if err := rows.Scan(&rk.AthleteID, &rk.Name, &rk.Gender, &rk.Grade, &privacy,
+			&rk.ResultID, &rk.Time, &rk.MeetID, &rk.MeetName, &rk.Distance); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		sec, err := parseRaceTime(rk.Time)
+		if err != nil {
+			continue
+		}
+		rk.seconds = sec
+		if public {
+			a := Athlete{Name: rk.Name, Gender: rk.Gender, Grade: rk.Grade, Privacy: privacy}
+			if !redactAthlete(&a) || (gender != "" && a.Gender == "") {
+				continue
+			}
+			rk.Name, rk.Gender, rk.Grade = a.Name, a.Gender, a.Grade
+		} else if av, ok := availability[rk.AthleteID]; ok {
+			if onlyAvailable && !av.availableOn(today) {
+				continue
+			}
+			rk.Availability, rk.ReturnDate = av.Status, av.ReturnDate
+		}
+		if cur, ok := best[rk.AthleteID]; !ok || sec < cur.seconds {
+			best[rk.AthleteID] = &rk
+		}
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	rankings := make([]Ranking, 0, len(best))
+	for _, rk := range best {
+		rk.PacePerMile, rk.PacePerKm = paces(rk.Time, rk.Distance)
+		rankings = append(rankings, *rk)
+	}
+	sort.Slice(rankings, func(i, j int) bool {
+		// Athletes without a gender come after both gendered lists.
+		if gi, gj := rankings[i].Gender, rankings[j].Gender; gi != gj {
+			return gj == "" || (gi != "" && gi < gj)
+		}
+		if rankings[i].seconds != rankings[j].seconds {
+			return rankings[i].seconds < rankings[j].seconds
+		}
+		return rankings[i].Name < rankings[j].Name
+	})
+	for i := range rankings {
+		rankings[i].Rank = 1
+		if i > 0 && rankings[i].Gender == rankings[i-1].Gender {
+			rankings[i].Rank = rankings[i-1].Rank + 1
+		}
+	}
+	json.NewEncoder(w).Encode(rankings)
+}
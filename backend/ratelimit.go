@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Login rate limiting ---
+//
+// Failed logins are counted per client IP and per username. The first few
+// failures are free; after that each key must wait an exponentially growing
+// delay before the next attempt, and once it reaches the configured maximum it
+// is locked out entirely. A successful login clears both counters.
+//
+// Usernames are whatever clients send, so the number of keys is capped. When
+// it fills up, keys that aren't currently blocked are forgotten early; if
+// every key is blocked, new ones go untracked until some expire, but the
+// blocked ones are never dropped to make room.
+
+const (
+	loginFreeAttempts = 3
+	loginStateTTL     = 24 * time.Hour
+	loginPruneEvery   = time.Minute
+	maxLoginKeys      = 100_000
+)
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	blockedTill time.Time
+}
+
+type loginLimiter struct {
+	mu        sync.Mutex
+	keys      map[string]*loginAttempts
+	lastPrune time.Time
+}
+
+var logins = &loginLimiter{keys: make(map[string]*loginAttempts)}
+
+// retryAfter returns how long the caller must wait before another attempt for
+// any of keys, or zero if it may try now.
+func (l *loginLimiter) retryAfter(now time.Time, keys ...string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+
+	var wait time.Duration
+	for _, k := range keys {
+		if a, ok := l.keys[k]; ok && a.blockedTill.After(now) {
+			wait = max(wait, a.blockedTill.Sub(now))
+		}
+	}
+	return wait
+}
+
+// fail records a failed attempt for each key and returns the highest failure
+// count among them.
+func (l *loginLimiter) fail(now time.Time, keys ...string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	worst := 0
+	for _, k := range keys {
+		a, ok := l.keys[k]
+		if !ok {
+			if len(l.keys) >= maxLoginKeys && !l.makeRoom(now) {
+				continue
+			}
+			a = &loginAttempts{}
+			l.keys[k] = a
+		}
+		a.failures++
+		a.lastFailure = now
+		a.blockedTill = now.Add(loginBackoff(a.failures))
+		worst = max(worst, a.failures)
+	}
+	return worst
+}
+
+func (l *loginLimiter) reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range keys {
+		delete(l.keys, k)
+	}
+}
+
+// prune forgets keys that have been quiet for a day. Callers hold l.mu.
+func (l *loginLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < loginPruneEvery {
+		return
+	}
+	l.lastPrune = now
+	for k, a := range l.keys {
+		if now.Sub(a.lastFailure) > loginStateTTL && !a.blockedTill.After(now) {
+			delete(l.keys, k)
+		}
+	}
+}
+
+// makeRoom forgets every key that isn't blocked right now and reports
+// whether there is space for another. Callers hold l.mu.
+func (l *loginLimiter) makeRoom(now time.Time) bool {
+	for k, a := range l.keys {
+		if !a.blockedTill.After(now) {
+			delete(l.keys, k)
+		}
+	}
+	return len(l.keys) < maxLoginKeys
+}
+
+// loginBackoff is the wait imposed after the given number of consecutive
+// failures: nothing for the first few, then 1s, 2s, 4s, ... up to the
+// lockout, which applies in full from the configured maximum onwards.
+func loginBackoff(failures int) time.Duration {
+	if failures >= cfg.LoginMaxFailures {
+		return cfg.LoginLockout
+	}
+	if failures < loginFreeAttempts {
+		return 0
+	}
+	d := time.Duration(math.Pow(2, float64(failures-loginFreeAttempts))) * time.Second
+	return min(d, cfg.LoginLockout)
+}
+
+// clientIP returns the address the request came from. X-Forwarded-For is only
+// honoured when a trusted reverse proxy sits in front of the server, since
+// clients can set it to anything.
+func clientIP(r *http.Request) string {
+	if cfg.TrustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			parts := strings.Split(fwd, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Messages for writeRateLimited.
+const (
+	tooManyLogins = "Too many failed login attempts"
+	tooManyResets = "Too many password reset requests"
+)
+
+// writeRateLimited sends a 429 with msg, telling the client when to retry.
+func writeRateLimited(w http.ResponseWriter, r *http.Request, msg string, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeError(w, r, &APIError{
+		Status:  http.StatusTooManyRequests,
+		Code:    codeRateLimited,
+		Message: msg + "; try again in " + strconv.Itoa(secs) + "s",
+	})
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// --- Varsity selection ---
+//
+// The selection splits each gender into varsity and JV by one set of team
+// rules. Athletes are ranked by a mark taken from their results in the past
+// year, converted to 5k as for meet entries: the best of their last N races,
+// or their season best. The fastest eligible athletes fill the varsity spots;
+// an athlete is ineligible when out today or below the minimum attendance
+// over the last few weeks. A coach override puts an athlete on varsity or JV
+// regardless, and an override to varsity takes one of the spots. Every
+// athlete comes back with the reasons for where they landed. Staff only.
+
+const (
+	selectionRecent = "recent"
+	selectionSeason = "season"
+
+	placementVarsity = "varsity"
+	placementJV      = "jv"
+)
+
+var (
+	selectionMethods = []string{selectionRecent, selectionSeason}
+	placements       = []string{placementVarsity, placementJV}
+)
+
+type SelectionRules struct {
+	// Method is "recent" (best of the last Races races) or "season".
+	Method string `json:"method"`
+	Races  int    `json:"races"`
+	// MinAttendance is a percentage over the last AttendanceWeeks; zero
+	// turns the check off.
+	MinAttendance   float64 `json:"min_attendance"`
+	AttendanceWeeks int     `json:"attendance_weeks"`
+	VarsitySize     int     `json:"varsity_size"`
+	UpdatedBy       string  `json:"updated_by,omitempty"`
+	Version         int     `json:"version"`
+}
+
+// defaultSelectionRules apply until a coach saves some.
+var defaultSelectionRules = SelectionRules{
+	Method:          selectionRecent,
+	Races:           recentRaces,
+	AttendanceWeeks: defaultTrainingWeeks,
+	VarsitySize:     7,
+}
+
+type SelectionOverride struct {
+	AthleteID int       `json:"athleteId"`
+	Name      string    `json:"name,omitempty"`
+	Placement string    `json:"placement"`
+	Note      string    `json:"note,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// Selected is one athlete's place in the selection.
+type Selected struct {
+	AthleteID    int      `json:"athleteId"`
+	Name         string   `json:"name"`
+	Grade        int      `json:"grade"`
+	Rank         int      `json:"rank,omitempty"`
+	Mark         string   `json:"mark,omitempty"`
+	Races        int      `json:"races"`
+	Attendance   *float64 `json:"attendance"`
+	Availability string   `json:"availability"`
+	Override     string   `json:"override,omitempty"`
+	Placement    string   `json:"placement"`
+	Reasons      []string `json:"reasons"`
+
+	seconds    float64
+	eligible   bool
+	out        bool
+	returnDate string
+	note       string
+}
+
+type SelectionGroup struct {
+	Gender  string     `json:"gender"`
+	Varsity []Selected `json:"varsity"`
+	JV      []Selected `json:"jv"`
+}
+
+type Selection struct {
+	Date   string           `json:"date"`
+	Rules  SelectionRules   `json:"rules"`
+	Groups []SelectionGroup `json:"groups"`
+}
+
+func loadSelectionRules(ctx context.Context) (SelectionRules, error) {
+	rules := defaultSelectionRules
+	err := db.QueryRow(ctx,
+		`SELECT method, races, min_attendance, attendance_weeks, varsity_size, updated_by, version
+		 FROM selection_rules WHERE id = 1`).Scan(
+		&rules.Method, &rules.Races, &rules.MinAttendance, &rules.AttendanceWeeks, &rules.VarsitySize,
+		&rules.UpdatedBy, &rules.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defaultSelectionRules, nil
+	}
+	return rules, err
+}
+
+// mark picks an athlete's ranking time from their marks, newest first.
+func (rules SelectionRules) mark(marks []float64) (float64, int) {
+	if rules.Method == selectionRecent {
+		marks = marks[:min(rules.Races, len(marks))]
+	}
+	if len(marks) == 0 {
+		return 0, 0
+	}
+	return slices.Min(marks), len(marks)
+}
+
+// describe explains a mark taken from races results.
+func (rules SelectionRules) describe(races int) string {
+	if rules.Method == selectionSeason {
+		return "season best of " + plural(races, "race")
+	}
+	return "best of " + plural(races, "recent race")
+}
+
+// selectTeams splits one gender's athletes into varsity and JV. Each needs
+// its marks, attendance, availability and override filled in; the result
+// lists each team fastest first.
+func selectTeams(rules SelectionRules, athletes []Selected, marks map[int][]float64) (varsity, jv []Selected) {
+	for i := range athletes {
+		a := &athletes[i]
+		var best float64
+		if best, a.Races = rules.mark(marks[a.AthleteID]); a.Races > 0 {
+			a.seconds, a.Mark = best, formatRaceTime(best)
+		}
+	}
+	slices.SortFunc(athletes, func(a, b Selected) int {
+		if (a.Races == 0) != (b.Races == 0) {
+			if a.Races == 0 {
+				return 1
+			}
+			return -1
+		}
+		if c := cmp.Compare(a.seconds, b.seconds); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	spots := rules.VarsitySize
+	for i := range athletes {
+		a := &athletes[i]
+		a.Reasons = []string{}
+		a.eligible = true
+		if a.Races > 0 {
+			a.Rank = i + 1
+			a.Reasons = append(a.Reasons, fmt.Sprintf("ranked %d by %s at 5k, the %s", a.Rank, a.Mark, rules.describe(a.Races)))
+		} else {
+			a.eligible = false
+			a.Reasons = append(a.Reasons, "no results in the past year")
+		}
+		if a.out {
+			a.eligible = false
+			if a.returnDate != "" {
+				a.Reasons = append(a.Reasons, "out until "+a.returnDate)
+			} else {
+				a.Reasons = append(a.Reasons, "out")
+			}
+		}
+		if rules.MinAttendance > 0 && a.Attendance != nil && *a.Attendance < rules.MinAttendance {
+			a.eligible = false
+			a.Reasons = append(a.Reasons, fmt.Sprintf("attendance %g%% is below the %g%% minimum", *a.Attendance, rules.MinAttendance))
+		}
+		if a.Override == placementVarsity {
+			spots--
+		}
+	}
+
+	for i := range athletes {
+		a := &athletes[i]
+		switch {
+		case a.Override != "":
+			a.Placement = a.Override
+			reason := "coach override to " + a.Override
+			if a.note != "" {
+				reason += ": " + a.note
+			}
+			a.Reasons = append(a.Reasons, reason)
+		case !a.eligible:
+			a.Placement = placementJV
+		case spots > 0:
+			a.Placement = placementVarsity
+			spots--
+			a.Reasons = append(a.Reasons, "fastest eligible for an open varsity spot")
+		default:
+			a.Placement = placementJV
+			a.Reasons = append(a.Reasons, fmt.Sprintf("the %d varsity spots are taken", rules.VarsitySize))
+		}
+		if a.Placement == placementVarsity {
+			varsity = append(varsity, *a)
+		} else {
+			jv = append(jv, *a)
+		}
+	}
+	if varsity == nil {
+		varsity = []Selected{}
+	}
+	if jv == nil {
+		jv = []Selected{}
+	}
+	return varsity, jv
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// selectionHandler runs the selection for both genders, or ?gender=M|F.
+// Staff only.
+func selectionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	gender := r.URL.Query().Get("gender")
+	if gender != "" && gender != "M" && gender != "F" {
+		writeError(w, r, errBadRequest("gender must be M or F"))
+		return
+	}
+	rules, err := loadSelectionRules(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	marks, err := loadMarks(ctx, today)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	availability, err := loadAvailability(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	summaries, err := attendanceSummaries(ctx, today.AddDate(0, 0, -7*rules.AttendanceWeeks+1), today, 0)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	attendance := map[int]*float64{}
+	for _, s := range summaries {
+		attendance[s.AthleteID] = s.Percent
+	}
+	overrides, err := loadOverrides(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT id, name, grade, gender FROM athletes
+		 WHERE gender IN ('M', 'F') AND ($1::text = '' OR gender = $1)`, gender)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	byGender := map[string][]Selected{}
+	for rows.Next() {
+		var a Selected
+		var g string
+		if err := rows.Scan(&a.AthleteID, &a.Name, &a.Grade, &g); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		av, ok := availability[a.AthleteID]
+		if !ok {
+			av.Status = availabilityHealthy
+		}
+		a.Availability, a.returnDate, a.out = av.Status, av.ReturnDate, !av.availableOn(today)
+		a.Attendance = attendance[a.AthleteID]
+		if o, ok := overrides[a.AthleteID]; ok {
+			a.Override, a.note = o.Placement, o.Note
+		}
+		byGender[g] = append(byGender[g], a)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	sel := Selection{Date: today.Format("2006-01-02"), Rules: rules, Groups: []SelectionGroup{}}
+	for _, g := range []string{"F", "M"} {
+		if gender != "" && g != gender {
+			continue
+		}
+		group := SelectionGroup{Gender: g}
+		group.Varsity, group.JV = selectTeams(rules, byGender[g], marks)
+		sel.Groups = append(sel.Groups, group)
+	}
+	json.NewEncoder(w).Encode(sel)
+}
+
+// selectionRulesHandler reads and replaces the team's selection rules.
+// Staff only.
+func selectionRulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		rules, err := loadSelectionRules(ctx)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, rules.Version)
+		json.NewEncoder(w).Encode(rules)
+
+	case http.MethodPut:
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var rules SelectionRules
+		if !decodeValid(w, r, &rules) {
+			return
+		}
+		rules.UpdatedBy = currentPrincipal(r).Username
+		// The rules are a single row; the defaults are version 0, so a
+		// conditional write needs a saved set to match against.
+		var tag pgconn.CommandTag
+		var err error
+		if expected == 0 {
+			tag, err = db.Exec(ctx,
+				`INSERT INTO selection_rules (id, method, races, min_attendance, attendance_weeks, varsity_size, updated_by)
+				 VALUES (1, $1, $2, $3, $4, $5, $6)
+				 ON CONFLICT (id) DO UPDATE
+				 SET method = EXCLUDED.method, races = EXCLUDED.races, min_attendance = EXCLUDED.min_attendance,
+				     attendance_weeks = EXCLUDED.attendance_weeks, varsity_size = EXCLUDED.varsity_size,
+				     updated_by = EXCLUDED.updated_by, version = selection_rules.version + 1, updated_at = CURRENT_TIMESTAMP`,
+				rules.Method, rules.Races, rules.MinAttendance, rules.AttendanceWeeks, rules.VarsitySize, rules.UpdatedBy)
+		} else {
+			tag, err = db.Exec(ctx,
+				`UPDATE selection_rules SET method = $1, races = $2, min_attendance = $3, attendance_weeks = $4,
+				 varsity_size = $5, updated_by = $6, version = version + 1, updated_at = CURRENT_TIMESTAMP
+				 WHERE id = 1 AND version = $7`,
+				rules.Method, rules.Races, rules.MinAttendance, rules.AttendanceWeeks, rules.VarsitySize, rules.UpdatedBy, expected)
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeError(w, r, errPreconditionFailed())
+			return
+		}
+		if rules, err = loadSelectionRules(ctx); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, rules.Version)
+		json.NewEncoder(w).Encode(rules)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+func loadOverrides(ctx context.Context) (map[int]SelectionOverride, error) {
+	rows, err := db.Query(ctx,
+		`SELECT o.athlete_id, a.name, o.placement, COALESCE(o.note, ''), o.created_by, o.created_at
+		 FROM selection_overrides o JOIN athletes a ON a.id = o.athlete_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := map[int]SelectionOverride{}
+	for rows.Next() {
+		var o SelectionOverride
+		if err := rows.Scan(&o.AthleteID, &o.Name, &o.Placement, &o.Note, &o.CreatedBy, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		overrides[o.AthleteID] = o
+	}
+	return overrides, rows.Err()
+}
+
+// selectionOverridesHandler manages coach overrides: GET lists them, PUT
+// ?athleteId= sets one and DELETE ?athleteId= clears it. Staff only.
+func selectionOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	var athleteID int
+	if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+		var err error
+		if athleteID, err = strconv.Atoi(r.URL.Query().Get("athleteId")); err != nil {
+			writeError(w, r, errBadRequest("athleteId parameter required"))
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		overrides, err := loadOverrides(ctx)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		list := make([]SelectionOverride, 0, len(overrides))
+		for _, o := range overrides {
+			list = append(list, o)
+		}
+		slices.SortFunc(list, func(a, b SelectionOverride) int { return cmp.Compare(a.Name, b.Name) })
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPut:
+		var o SelectionOverride
+		if !decodeValid(w, r, &o) {
+			return
+		}
+		o.AthleteID = athleteID
+		o.CreatedBy = currentPrincipal(r).Username
+		err := db.QueryRow(ctx,
+			`INSERT INTO selection_overrides (athlete_id, placement, note, created_by) VALUES ($1, $2, NULLIF($3, ''), $4)
+			 ON CONFLICT (athlete_id) DO UPDATE
+			 SET placement = EXCLUDED.placement, note = EXCLUDED.note, created_by = EXCLUDED.created_by, created_at = CURRENT_TIMESTAMP
+			 RETURNING created_at`,
+			o.AthleteID, o.Placement, o.Note, o.CreatedBy).Scan(&o.CreatedAt)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		json.NewEncoder(w).Encode(o)
+
+	case http.MethodDelete:
+		tag, err := db.Exec(ctx, "DELETE FROM selection_overrides WHERE athlete_id = $1", athleteID)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeError(w, r, errNotFound("Override not found"))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
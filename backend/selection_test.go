@@ -0,0 +1,132 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func selectedNames(list []Selected) []string {
+	out := []string{}
+	for _, a := range list {
+		out = append(out, a.Name)
+	}
+	return out
+}
+
+func TestSelectTeams(t *testing.T) {
+	pct := func(v float64) *float64 { return &v }
+	rules := SelectionRules{Method: selectionSeason, VarsitySize: 2}
+
+	tests := []struct {
+		name     string
+		rules    SelectionRules
+		athletes []Selected
+		// Marks are newest first, by athlete ID.
+		marks       map[int][]float64
+		varsity, jv []string
+	}{
+		{
+			name:     "fastest fill the spots",
+			rules:    rules,
+			athletes: []Selected{{AthleteID: 1, Name: "Cole"}, {AthleteID: 2, Name: "Avery"}, {AthleteID: 3, Name: "Blake"}},
+			marks:    map[int][]float64{1: {1100}, 2: {1000}, 3: {1050}},
+			varsity:  []string{"Avery", "Blake"},
+			jv:       []string{"Cole"},
+		},
+		{
+			name:     "equal marks break by name",
+			rules:    SelectionRules{Method: selectionSeason, VarsitySize: 1},
+			athletes: []Selected{{AthleteID: 1, Name: "Dana"}, {AthleteID: 2, Name: "Casey"}},
+			marks:    map[int][]float64{1: {1000}, 2: {1000}},
+			varsity:  []string{"Casey"},
+			jv:       []string{"Dana"},
+		},
+		{
+			name:  "ineligible athletes don't take spots",
+			rules: SelectionRules{Method: selectionSeason, VarsitySize: 2, MinAttendance: 80},
+			athletes: []Selected{
+				{AthleteID: 1, Name: "Out", out: true, returnDate: "2026-11-01"},
+				{AthleteID: 2, Name: "Absent", Attendance: pct(50)},
+				{AthleteID: 3, Name: "Unraced"},
+				{AthleteID: 4, Name: "Steady", Attendance: pct(90)},
+				{AthleteID: 5, Name: "Newcomer"},
+			},
+			marks:   map[int][]float64{1: {900}, 2: {950}, 4: {1100}, 5: {1200}},
+			varsity: []string{"Steady", "Newcomer"},
+			jv:      []string{"Out", "Absent", "Unraced"},
+		},
+		{
+			name:  "overrides place athletes and varsity ones use a spot",
+			rules: rules,
+			athletes: []Selected{
+				{AthleteID: 1, Name: "Fastest", Override: placementJV},
+				{AthleteID: 2, Name: "Second"},
+				{AthleteID: 3, Name: "Third"},
+				{AthleteID: 4, Name: "Picked", Override: placementVarsity},
+			},
+			marks:   map[int][]float64{1: {900}, 2: {1000}, 3: {1010}, 4: {1300}},
+			varsity: []string{"Second", "Picked"},
+			jv:      []string{"Fastest", "Third"},
+		},
+		{
+			name:     "recent method only looks at the last races",
+			rules:    SelectionRules{Method: selectionRecent, Races: 2, VarsitySize: 1},
+			athletes: []Selected{{AthleteID: 1, Name: "Faded"}, {AthleteID: 2, Name: "Consistent"}},
+			marks:    map[int][]float64{1: {1200, 1150, 950}, 2: {1100, 1120, 1090}},
+			varsity:  []string{"Consistent"},
+			jv:       []string{"Faded"},
+		},
+		{
+			name:     "season method takes the best of all",
+			rules:    SelectionRules{Method: selectionSeason, VarsitySize: 1},
+			athletes: []Selected{{AthleteID: 1, Name: "Faded"}, {AthleteID: 2, Name: "Consistent"}},
+			marks:    map[int][]float64{1: {1200, 1150, 950}, 2: {1100, 1120, 1090}},
+			varsity:  []string{"Faded"},
+			jv:       []string{"Consistent"},
+		},
+		{
+			name:     "no athletes",
+			rules:    rules,
+			athletes: nil,
+			varsity:  []string{},
+			jv:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		varsity, jv := selectTeams(tt.rules, tt.athletes, tt.marks)
+		if got := selectedNames(varsity); !slices.Equal(got, tt.varsity) {
+			t.Errorf("%s: varsity = %v, want %v", tt.name, got, tt.varsity)
+		}
+		if got := selectedNames(jv); !slices.Equal(got, tt.jv) {
+			t.Errorf("%s: jv = %v, want %v", tt.name, got, tt.jv)
+		}
+	}
+}
+
+func TestSelectTeamsReasons(t *testing.T) {
+	rules := SelectionRules{Method: selectionSeason, VarsitySize: 1}
+	athletes := []Selected{
+		{AthleteID: 1, Name: "Quick"},
+		{AthleteID: 2, Name: "Slow"},
+		{AthleteID: 3, Name: "Hurt", out: true, returnDate: "2026-11-01"},
+	}
+	varsity, jv := selectTeams(rules, athletes, map[int][]float64{1: {1000}, 2: {1100}, 3: {1200}})
+
+	tests := []struct {
+		got  Selected
+		want []string
+	}{
+		{varsity[0], []string{"ranked 1 by 16:40 at 5k", "open varsity spot"}},
+		{jv[0], []string{"ranked 2 by 18:20 at 5k", "the 1 varsity spots are taken"}},
+		{jv[1], []string{"ranked 3", "out until 2026-11-01"}},
+	}
+	for _, tt := range tests {
+		reasons := strings.Join(tt.got.Reasons, "; ")
+		for _, w := range tt.want {
+			if !strings.Contains(reasons, w) {
+				t.Errorf("%s: reasons %q don't mention %q", tt.got.Name, reasons, w)
+			}
+		}
+	}
+}
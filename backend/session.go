@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// --- Cookie sessions ---
+//
+// With session_cookies on, login puts the access token in an HttpOnly cookie
+// rather than the response body, so script on the page (including an XSS in
+// the public views) can never read it. Because the browser attaches the cookie
+// by itself, writes must echo the session's CSRF token in X-CSRF-Token; the
+// token is a claim inside the signed session and is also set in a readable
+// cookie for the frontend (double submit). Bearer tokens keep working for API
+// clients either way.
+
+const (
+	sessionCookie = "xc_session"
+	csrfCookie    = "xc_csrf"
+	csrfHeader    = "X-CSRF-Token"
+)
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func validCSRF(r *http.Request, c *Claims) bool {
+	sent := r.Header.Get(csrfHeader)
+	return sent != "" && c.CSRF != "" && hmac.Equal([]byte(sent), []byte(c.CSRF))
+}
+
+// cookieSecure reports whether session cookies get the Secure attribute.
+// Plain-HTTP development servers would otherwise never see them come back.
+func cookieSecure(r *http.Request) bool {
+	return cfg.Production || r.TLS != nil || (cfg.TrustProxy && r.Header.Get("X-Forwarded-Proto") == "https")
+}
+
+func setSessionCookies(w http.ResponseWriter, r *http.Request, token, csrf string, maxAge int) {
+	secure := cookieSecure(r)
+	http.SetCookie(w, &http.Cookie{
+		Name: sessionCookie, Value: token, Path: "/", MaxAge: maxAge,
+		HttpOnly: true, Secure: secure, SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name: csrfCookie, Value: csrf, Path: "/", MaxAge: maxAge,
+		Secure: secure, SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// writeSession completes a successful login: a bearer token in the body, or
+// in cookie mode the session cookies plus the CSRF token.
+func writeSession(w http.ResponseWriter, r *http.Request, username string, acct account) {
+	if !cfg.SessionCookies {
+		json.NewEncoder(w).Encode(map[string]string{"token": issueToken(username, acct)})
+		return
+	}
+
+	claims := accessClaims(username, acct)
+	claims.CSRF = newTokenID()
+	setSessionCookies(w, r, tokenKeys.sign(claims), claims.CSRF, int(cfg.TokenTTL.Seconds()))
+	json.NewEncoder(w).Encode(map[string]any{
+		"username":   username,
+		"role":       acct.Role,
+		"csrf_token": claims.CSRF,
+		"expires_at": time.Unix(claims.ExpiresAt, 0).UTC(),
+	})
+}
+
+// sessionHandler tells the frontend who is signed in; in cookie mode it has
+// no other way to find out after a reload.
+func sessionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	p := currentPrincipal(r)
+	json.NewEncoder(w).Encode(map[string]any{"username": p.Username, "role": p.Role, "athlete_id": p.AthleteID})
+}
+
+// logoutHandler clears the session cookies. Bearer clients just drop their
+// token.
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	setSessionCookies(w, r, "", "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Splits ---
+//
+// A result's splits are its elapsed times at checkpoints along the course
+// (the 1-mile and 2-mile marks, usually). They are recorded as a set through
+// /api/result-splits and count as part of the result: replacing them bumps
+// the result's version. Lap times, paces and differentials are computed on
+// the way out rather than stored.
+
+type Split struct {
+	ID       int     `json:"id"`
+	ResultID int     `json:"resultId"`
+	Label    string  `json:"label"`
+	Distance float64 `json:"distance_m"`
+	Elapsed  string  `json:"elapsed"`
+	// Computed: the time and per-mile pace since the previous checkpoint (or
+	// the start), and how much slower (+) or faster (-) that pace was than the
+	// lap before.
+	Lap          string `json:"lap,omitempty"`
+	LapPace      string `json:"lap_pace,omitempty"`
+	Differential string `json:"differential,omitempty"`
+}
+
+// resultSplits is the body of a PUT to /api/result-splits.
+type resultSplits struct {
+	Splits []Split `json:"splits"`
+}
+
+// formatDifferential renders a signed pace difference such as "+0:04" or
+// "-0:02.5".
+func formatDifferential(sec float64) string {
+	if sec < 0 {
+		return "-" + formatRaceTime(sec)
+	}
+	return "+" + formatRaceTime(sec)
+}
+
+// computeLaps fills in the derived fields of splits, which must be ordered
+// by distance.
+func computeLaps(splits []Split) {
+	var prevDist, prevElapsed, prevPace float64
+	for i := range splits {
+		s := &splits[i]
+		elapsed, err := parseRaceTime(s.Elapsed)
+		if err != nil || s.Distance <= prevDist || elapsed <= prevElapsed {
+			// Only stored splits get here, and those were validated.
+			continue
+		}
+		lap := elapsed - prevElapsed
+		pace := lap / ((s.Distance - prevDist) / metersPerMile)
+		s.Lap = formatRaceTime(lap)
+		s.LapPace = formatRaceTime(pace)
+		if i > 0 && prevPace > 0 {
+			s.Differential = formatDifferential(pace - prevPace)
+		}
+		prevDist, prevElapsed, prevPace = s.Distance, elapsed, pace
+	}
+}
+
+// checkSplitTimes makes sure the elapsed times, already validated as race
+// times, strictly increase and none is after the finish. The first split
+// out of line is reported as a 400 naming its index.
+func checkSplitTimes(splits []Split, finish string) *APIError {
+	total, err := parseRaceTime(finish)
+	if err != nil {
+		// Results validated before times were strict may not parse; the
+		// order can still be checked.
+		total = math.Inf(1)
+	}
+	prev := 0.0
+	for i, s := range splits {
+		elapsed, _ := parseRaceTime(s.Elapsed)
+		var msg string
+		switch {
+		case elapsed <= prev:
+			msg = "must be later than the previous checkpoint's"
+		case elapsed > total:
+			msg = "must not be after the finish time " + finish
+		default:
+			prev = elapsed
+			continue
+		}
+		field := fmt.Sprintf("splits[%d].elapsed", i)
+		return &APIError{Status: http.StatusBadRequest, Code: codeBadRequest, Message: field + " " + msg,
+			Fields: ValidationErrors{{Field: field, Message: msg}}}
+	}
+	return nil
+}
+
+// loadSplits returns the splits selected by where (a condition on the results
+// table aliased r), grouped by result with laps computed.
+func loadSplits(ctx context.Context, where string, args ...any) (map[int][]Split, error) {
+	rows, err := db.Query(ctx,
+		`SELECT s.id, s.result_id, s.label, s.distance_m, s.elapsed
+		 FROM result_splits s JOIN results r ON r.id = s.result_id
+		 WHERE `+where+` ORDER BY s.result_id, s.distance_m`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byResult := map[int][]Split{}
+	for rows.Next() {
+		var s Split
+		if err := rows.Scan(&s.ID, &s.ResultID, &s.Label, &s.Distance, &s.Elapsed); err != nil {
+			return nil, err
+		}
+		byResult[s.ResultID] = append(byResult[s.ResultID], s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, splits := range byResult {
+		computeLaps(splits)
+	}
+	return byResult, nil
+}
+
+// resultSplitsHandler reads and replaces the splits of one result, given as
+// ?resultId=.
+func resultSplitsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	resultID, err := strconv.Atoi(r.URL.Query().Get("resultId"))
+	if err != nil {
+		writeError(w, r, errBadRequest("resultId parameter required"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var version int
+		err := db.QueryRow(ctx, "SELECT version FROM results WHERE id = $1", resultID).Scan(&version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, r, errNotFound("Result not found"))
+			return
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		byResult, err := loadSplits(ctx, "r.id = $1", resultID)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		splits := byResult[resultID]
+		if splits == nil {
+			splits = []Split{}
+		}
+		setETag(w, version)
+		json.NewEncoder(w).Encode(splits)
+
+	case http.MethodPut:
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var body resultSplits
+		if !decodeValid(w, r, &body) {
+			return
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		var finish string
+		var version int
+		err = tx.QueryRow(ctx,
+			`UPDATE results SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+			 WHERE id = $1 AND ($2::int = 0 OR version = $2) RETURNING time, version`,
+			resultID, expected).Scan(&finish, &version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeMissedWrite(w, r, "results", resultID, expected, "Result not found")
+			return
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if e := checkSplitTimes(body.Splits, finish); e != nil {
+			writeError(w, r, e)
+			return
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM result_splits WHERE result_id = $1", resultID); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		for i := range body.Splits {
+			s := &body.Splits[i]
+			s.ResultID = resultID
+			err := tx.QueryRow(ctx,
+				"INSERT INTO result_splits (result_id, label, distance_m, elapsed) VALUES ($1, $2, $3, $4) RETURNING id",
+				resultID, s.Label, s.Distance, s.Elapsed).Scan(&s.ID)
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+
+		splits := body.Splits
+		if splits == nil {
+			splits = []Split{}
+		}
+		computeLaps(splits)
+		setETag(w, version)
+		json.NewEncoder(w).Encode(splits)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
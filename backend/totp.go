@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Two-factor authentication (TOTP, RFC 6238) ---
+//
+// Enrollment is optional per user: POST /api/2fa/setup issues a secret and an
+// otpauth:// URI for the authenticator app's QR scanner, and
+// POST /api/2fa/enable confirms it with a first code and returns one-time
+// recovery codes. Once enabled, /api/login only returns a short-lived MFA
+// token, which /api/login/2fa exchanges for a full token given a valid code.
+
+const (
+	totpIssuer        = "Jones County XC"
+	totpPeriod        = 30
+	totpDigits        = 6
+	totpSkew          = 1 // accept codes one step either side for clock drift
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// hotp computes the RFC 4226 one-time password for counter.
+func hotp(secret []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, bin%1000000)
+}
+
+// verifyTOTP checks code against the steps around now and returns the
+// matching step, which callers record to stop the same code being replayed.
+func verifyTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	step := now.Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		s := step + int64(i)
+		if hmac.Equal([]byte(hotp(secret, uint64(s))), []byte(code)) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+func totpURI(username, secret string) string {
+	label := url.PathEscape(totpIssuer + ":" + username)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	// Some authenticator apps show a literal "+" rather than a space.
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(q.Encode(), "+", "%20")
+}
+
+// newRecoveryCodes returns human-friendly codes like "k3vq7-2mxpa" along with
+// the hashes to store. The codes carry enough entropy that a plain SHA-256
+// is sufficient.
+func newRecoveryCodes() (codes, hashes []string) {
+	enc := base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+	for range recoveryCodeCount {
+		b := make([]byte, 7)
+		rand.Read(b)
+		s := enc.EncodeToString(b)[:10]
+		codes = append(codes, s[:5]+"-"+s[5:])
+		hashes = append(hashes, hashRecoveryCode(s))
+	}
+	return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// totpEnabled reports whether username must pass a second factor to log in.
+func totpEnabled(ctx context.Context, username string) (bool, error) {
+	var enabled bool
+	err := db.QueryRow(ctx, "SELECT enabled FROM user_totp WHERE username = $1", username).Scan(&enabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. Both are consumed atomically so a code can't be used twice.
+func checkSecondFactor(ctx context.Context, username, code, recoveryCode string, requireEnabled bool) (bool, error) {
+	if recoveryCode != "" {
+		tag, err := db.Exec(ctx,
+			`UPDATE totp_recovery_codes SET used_at = CURRENT_TIMESTAMP
+			 WHERE username = $1 AND code_hash = $2 AND used_at IS NULL`,
+			username, hashRecoveryCode(recoveryCode))
+		if err != nil {
+			return false, err
+		}
+		return tag.RowsAffected() == 1, nil
+	}
+
+	var secret string
+	var enabled bool
+	err := db.QueryRow(ctx, "SELECT secret, enabled FROM user_totp WHERE username = $1", username).Scan(&secret, &enabled)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && enabled != requireEnabled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := verifyTOTP(key, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	tag, err := db.Exec(ctx,
+		"UPDATE user_totp SET last_step = $2 WHERE username = $1 AND last_step < $2", username, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+type twoFactorRequest struct {
+	MFAToken     string `json:"mfa_token,omitempty"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// secondFactorLimitKeys are the login limiter keys for a code check. Every
+// endpoint that accepts a code uses them, so a stolen session can't guess
+// codes through enable or disable faster than through the login itself.
+func secondFactorLimitKeys(r *http.Request, username string) []string {
+	return []string{"ip:" + clientIP(r), "user:" + strings.ToLower(username)}
+}
+
+// loginMFAHandler completes a two-step login.
+func loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errBadRequest("Invalid request body"))
+		return
+	}
+	username, acct, ok := validateMFAToken(req.MFAToken)
+	if !ok {
+		writeError(w, r, errUnauthorized("Login session expired; sign in again"))
+		return
+	}
+
+	ip := clientIP(r)
+	limitKeys := secondFactorLimitKeys(r, username)
+	now := time.Now()
+	if wait := logins.retryAfter(now, limitKeys...); wait > 0 {
+		writeRateLimited(w, r, tooManyLogins, wait)
+		return
+	}
+
+	ok, err := checkSecondFactor(ctx, username, req.Code, req.RecoveryCode, true)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !ok {
+		failures := logins.fail(now, limitKeys...)
+		log.Printf("2FA failed for %q from %s (%d consecutive failures)", username, ip, failures)
+		writeError(w, r, errUnauthorized("Invalid code"))
+		return
+	}
+	logins.reset(limitKeys...)
+	if req.RecoveryCode != "" {
+		log.Printf("recovery code used for %q from %s", username, ip)
+	}
+
+	writeSession(w, r, username, acct)
+}
+
+// twoFactorStatusHandler reports whether the caller has 2FA enabled.
+func twoFactorStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	username := currentPrincipal(r).Username
+	enabled, err := totpEnabled(ctx, username)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": enabled})
+}
+
+// twoFactorSetupHandler starts (or restarts) enrollment with a new secret.
+func twoFactorSetupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	username := currentPrincipal(r).Username
+	raw := make([]byte, 20)
+	rand.Read(raw)
+	secret := totpEncoding.EncodeToString(raw)
+
+	tag, err := db.Exec(ctx,
+		`INSERT INTO user_totp (username, secret) VALUES ($1, $2)
+		 ON CONFLICT (username) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0
+		 WHERE user_totp.enabled = FALSE`,
+		username, secret)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, r, errConflict("Two-factor authentication is already enabled"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"secret": secret, "uri": totpURI(username, secret)})
+}
+
+// twoFactorEnableHandler confirms enrollment with a code from the app and
+// returns the recovery codes. They are shown exactly once.
+func twoFactorEnableHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errBadRequest("Invalid request body"))
+		return
+	}
+	username := currentPrincipal(r).Username
+	limitKeys := secondFactorLimitKeys(r, username)
+	now := time.Now()
+	if wait := logins.retryAfter(now, limitKeys...); wait > 0 {
+		writeRateLimited(w, r, tooManyLogins, wait)
+		return
+	}
+
+	ok, err := checkSecondFactor(ctx, username, req.Code, "", false)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !ok {
+		failures := logins.fail(now, limitKeys...)
+		log.Printf("2FA enable code failed for %q from %s (%d consecutive failures)", username, clientIP(r), failures)
+		writeError(w, r, errValidation(ValidationErrors{{Field: "code", Message: "is not valid for the pending secret"}}))
+		return
+	}
+	logins.reset(limitKeys...)
+
+	codes, hashes := newRecoveryCodes()
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE user_totp SET enabled = TRUE WHERE username = $1", username); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM totp_recovery_codes WHERE username = $1", username); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	for _, h := range hashes {
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO totp_recovery_codes (username, code_hash) VALUES ($1, $2)", username, h); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	log.Printf("2FA enabled for %q", username)
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+}
+
+// twoFactorDisableHandler turns 2FA off after re-checking a code, so a stolen
+// session token alone can't remove the second factor.
+func twoFactorDisableHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errBadRequest("Invalid request body"))
+		return
+	}
+	username := currentPrincipal(r).Username
+	limitKeys := secondFactorLimitKeys(r, username)
+	now := time.Now()
+	if wait := logins.retryAfter(now, limitKeys...); wait > 0 {
+		writeRateLimited(w, r, tooManyLogins, wait)
+		return
+	}
+
+	ok, err := checkSecondFactor(ctx, username, req.Code, req.RecoveryCode, true)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	if !ok {
+		failures := logins.fail(now, limitKeys...)
+		log.Printf("2FA disable code failed for %q from %s (%d consecutive failures)", username, clientIP(r), failures)
+		writeError(w, r, errUnauthorized("Invalid code"))
+		return
+	}
+	logins.reset(limitKeys...)
+	if _, err := db.Exec(ctx, "DELETE FROM user_totp WHERE username = $1", username); err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	log.Printf("2FA disabled for %q", username)
+	w.WriteHeader(http.StatusNoContent)
+}
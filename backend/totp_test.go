@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the shared secret of the RFC 4226 and RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := hotp(rfcSecret, uint64(counter)); got != code {
+			t.Errorf("hotp(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestVerifyTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B (SHA-1), cut to our six digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		step, ok := verifyTOTP(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("verifyTOTP(%s) at %d rejected", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / totpPeriod; step != want {
+			t.Errorf("verifyTOTP(%s) at %d = step %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	now := time.Unix(1_700_000_015, 0)
+	step := now.Unix() / totpPeriod
+	tests := []struct {
+		name   string
+		offset int64
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"one step behind", -1, true},
+		{"one step ahead", 1, true},
+		{"two steps behind", -2, false},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		code := hotp(rfcSecret, uint64(step+tt.offset))
+		got, ok := verifyTOTP(rfcSecret, code, now)
+		if ok != tt.ok {
+			t.Errorf("%s: accepted = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && got != step+tt.offset {
+			t.Errorf("%s: step = %d, want %d", tt.name, got, step+tt.offset)
+		}
+	}
+}
+
+func TestVerifyTOTPFormat(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := []struct {
+		code string
+		ok   bool
+	}{
+		{"287082", true},
+		{" 287082 ", true},
+		{"28708", false},
+		{"2870820", false},
+		{"", false},
+		{"abcdef", false},
+		{"287083", false},
+	}
+	for _, tt := range tests {
+		if _, ok := verifyTOTP(rfcSecret, tt.code, now); ok != tt.ok {
+			t.Errorf("verifyTOTP(%q) accepted = %v, want %v", tt.code, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Accounts ---
+//
+// Coaches sign in with accounts in the users table. The configured admin
+// account stays as a bootstrap login that needs no database row; it can create
+// the others through /api/users. Athlete and parent accounts are linked to an
+// athletes row and can only read that athlete's own data under /api/me.
+
+const (
+	roleCoach   = "coach"
+	roleAthlete = "athlete"
+	roleParent  = "parent"
+
+	passwordIterations = 600000
+	passwordMinLength  = 12
+)
+
+type User struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Password  string    `json:"password,omitempty"`
+	Role      string    `json:"role"`
+	AthleteID *int      `json:"athlete_id,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// account is what a login grants: a role and, for athlete and parent
+// accounts, the athlete it is scoped to.
+type account struct {
+	Role      string
+	AthleteID int
+}
+
+// hashPassword returns a PBKDF2-SHA256 hash in the form
+// pbkdf2-sha256$iterations$salt$key.
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+func verifyPassword(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	enc := base64.RawStdEncoding
+	salt, err1 := enc.DecodeString(parts[2])
+	want, err2 := enc.DecodeString(parts[3])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iter, len(want))
+	return err == nil && hmac.Equal(got, want)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// checkPassword verifies a login and returns what it grants. Unknown
+// usernames still pay for a hash so response times don't reveal which
+// accounts exist.
+func checkPassword(ctx context.Context, username, password string) (account, bool, error) {
+	if username == cfg.AdminUsername {
+		return account{Role: roleAdmin}, hmac.Equal([]byte(password), []byte(cfg.AdminPassword)), nil
+	}
+
+	var hash string
+	var acct account
+	var athleteID *int
+	err := db.QueryRow(ctx, "SELECT password_hash, role, athlete_id FROM users WHERE username = $1", username).
+		Scan(&hash, &acct.Role, &athleteID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		dummyHashOnce.Do(func() { dummyHash, _ = hashPassword("not a real password") })
+		verifyPassword(dummyHash, password)
+		return account{}, false, nil
+	}
+	if err != nil {
+		return account{}, false, err
+	}
+	if athleteID != nil {
+		acct.AthleteID = *athleteID
+	}
+	return acct, verifyPassword(hash, password), nil
+}
+
+// sessionRevoked reports whether a token predates the account's last
+// password change, or the account no longer exists. The configured admin has
+// no row and its tokens are only revoked by rotating the signing key.
+//
+// authMiddleware calls this on every request with a token, so it costs one
+// lookup on the unique username index each time. The answer isn't cached,
+// so a password change locks out old tokens on their very next request.
+func sessionRevoked(ctx context.Context, c *Claims) (bool, error) {
+	if c.Subject == cfg.AdminUsername {
+		return false, nil
+	}
+	var validAfter time.Time
+	err := db.QueryRow(ctx, "SELECT tokens_valid_after FROM users WHERE username = $1", c.Subject).Scan(&validAfter)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return c.IssuedAt < validAfter.Unix(), nil
+}
+
+// requireRole rejects callers without the given role. It runs after
+// requireAuth, so a principal is always present.
+func requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if currentPrincipal(r).Role != role {
+			writeError(w, r, errForbidden("Forbidden"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireStaff rejects athlete and parent accounts. It runs after
+// requireAuth.
+func requireStaff(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !currentPrincipal(r).isStaff() {
+			writeError(w, r, errForbidden("Forbidden"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// usersHandler manages accounts. Admin only.
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(ctx, "SELECT id, username, email, role, athlete_id, created_at FROM users ORDER BY username")
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		users := []User{}
+		for rows.Next() {
+			var u User
+			if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.Role, &u.AthleteID, &u.CreatedAt); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			users = append(users, u)
+		}
+		json.NewEncoder(w).Encode(users)
+
+	case http.MethodPost:
+		var u User
+		if !decodeValid(w, r, &u) {
+			return
+		}
+		if u.Username == cfg.AdminUsername {
+			writeError(w, r, errConflict("Username is reserved for the configured admin"))
+			return
+		}
+		hash, err := hashPassword(u.Password)
+		if err != nil {
+			writeError(w, r, errInternal())
+			return
+		}
+		err = db.QueryRow(ctx,
+			`INSERT INTO users (username, email, password_hash, role, athlete_id)
+			 VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+			u.Username, u.Email, hash, u.Role, u.AthleteID).Scan(&u.ID, &u.CreatedAt)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		u.Password = ""
+		log.Printf("user %q (%s) created by %q", u.Username, u.Role, currentPrincipal(r).Username)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(u)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		var username string
+		err = tx.QueryRow(ctx, "DELETE FROM users WHERE id = $1 RETURNING username", id).Scan(&username)
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, r, errNotFound("User not found"))
+			return
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		// 2FA is keyed by username with no foreign key, since the configured
+		// admin has no users row, so it goes here; otherwise an account
+		// created later under the same name would inherit it. Recovery codes
+		// cascade from user_totp.
+		if _, err := tx.Exec(ctx, "DELETE FROM user_totp WHERE username = $1", username); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Validation ---
+
+// FieldError describes a single invalid field in a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every problem found in a request body so the
+// client can fix them all at once instead of one round trip per field.
+type ValidationErrors []FieldError
+
+func (v *ValidationErrors) add(field, format string, args ...any) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *ValidationErrors) required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *ValidationErrors) maxLen(field, value string, max int) {
+	if len(value) > max {
+		v.add(field, "must be at most %d characters", max)
+	}
+}
+
+func (v *ValidationErrors) date(field, value string) {
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		v.add(field, "must be a date in YYYY-MM-DD format")
+	}
+}
+
+func (v *ValidationErrors) raceTime(field, value string) {
+	if _, err := parseRaceTime(value); err != nil {
+		v.add(field, "must be a time in MM:SS or H:MM:SS format")
+	}
+}
+
+func (v *ValidationErrors) password(field, value string) {
+	if len(value) < passwordMinLength {
+		v.add(field, "must be at least %d characters", passwordMinLength)
+	}
+	v.maxLen(field, value, 200)
+}
+
+type validator interface {
+	Validate() ValidationErrors
+}
+
+// parseRaceTime converts a finish time such as "17:45", "17:45.3" or
+// "1:02:10" into seconds. Every field is plain digits, and only the last may
+// have a decimal part, so strconv's extras ("+5", "1e2", "NaN", "Inf") are
+// not race times.
+func parseRaceTime(s string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid race time %q", s)
+	}
+
+	var total float64
+	for i, p := range parts {
+		whole, frac, hasFrac := strings.Cut(p, ".")
+		if !allDigits(whole) || (hasFrac && (i < len(parts)-1 || !allDigits(frac))) {
+			return 0, fmt.Errorf("invalid race time %q", s)
+		}
+		n, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid race time %q", s)
+		}
+		// Minutes and seconds after the leading field roll over at 60.
+		if i > 0 && n >= 60 {
+			return 0, fmt.Errorf("invalid race time %q", s)
+		}
+		total = total*60 + n
+	}
+	if math.IsInf(total, 0) {
+		return 0, fmt.Errorf("invalid race time %q", s)
+	}
+	return total, nil
+}
+
+// allDigits reports whether s is one or more ASCII digits.
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *Athlete) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("name", a.Name) {
+		errs.maxLen("name", a.Name, 100)
+	}
+	if a.Gender != "M" && a.Gender != "F" {
+		errs.add("gender", "must be M or F")
+	}
+	if a.Grade < 9 || a.Grade > 12 {
+		errs.add("grade", "must be between 9 and 12")
+	}
+	if a.PersonalRecord != "" {
+		errs.raceTime("personal_record", a.PersonalRecord)
+	}
+	errs.maxLen("events", a.Events, 200)
+	switch a.Privacy {
+	case "", privacyFull, privacyInitials, privacyHidden:
+	default:
+		errs.add("privacy", "must be full, initials or hidden")
+	}
+	return errs
+}
+
+func (m *Meet) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("name", m.Name) {
+		errs.maxLen("name", m.Name, 100)
+	}
+	if errs.required("date", m.Date) {
+		errs.date("date", m.Date)
+	}
+	errs.maxLen("location", m.Location, 100)
+	// Zero leaves the distance unknown.
+	if m.Distance != 0 && (m.Distance < minDistance || m.Distance > maxDistance) {
+		errs.add("distance_m", "must be between %g and %g meters", minDistance, maxDistance)
+	}
+	return errs
+}
+
+func (res *Result) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if res.AthleteID <= 0 {
+		errs.add("athleteId", "is required")
+	}
+	if res.MeetID <= 0 {
+		errs.add("meetId", "is required")
+	}
+	if errs.required("time", res.Time) {
+		errs.raceTime("time", res.Time)
+	}
+	// Place is optional (zero means unplaced), but never negative.
+	if res.Place < 0 {
+		errs.add("place", "must be greater than 0")
+	}
+	return errs
+}
+
+func (c *Coach) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("name", c.Name) {
+		errs.maxLen("name", c.Name, 100)
+	}
+	if errs.required("title", c.Title) {
+		errs.maxLen("title", c.Title, 100)
+	}
+	return errs
+}
+
+func (fm *FutureMeet) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("name", fm.Name) {
+		errs.maxLen("name", fm.Name, 100)
+	}
+	if errs.required("date", fm.Date) {
+		errs.date("date", fm.Date)
+	}
+	errs.maxLen("location", fm.Location, 100)
+	if fm.Level != "Varsity" && fm.Level != "JV" {
+		errs.add("level", "must be Varsity or JV")
+	}
+	return errs
+}
+
+// decodeValid decodes the request body into v and validates it. On failure it
+// writes a 400 (malformed JSON) or 422 (invalid fields) response and returns
+// false.
+func decodeValid(w http.ResponseWriter, r *http.Request, v validator) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, r, errBadRequest("Invalid request body"))
+		return false
+	}
+	if errs := v.Validate(); len(errs) > 0 {
+		writeError(w, r, errValidation(errs))
+		return false
+	}
+	return true
+}
+
+func (n *AthleteNote) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if n.AthleteID <= 0 {
+		errs.add("athleteId", "is required")
+	}
+	if errs.required("body", n.Body) {
+		errs.maxLen("body", n.Body, 5000)
+	}
+	return errs
+}
+
+func (u *User) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("username", u.Username) {
+		errs.maxLen("username", u.Username, 100)
+	}
+	if errs.required("email", u.Email) {
+		errs.maxLen("email", u.Email, 255)
+		if _, err := mail.ParseAddress(u.Email); err != nil || strings.ContainsAny(u.Email, "<>\r\n ") {
+			errs.add("email", "must be a valid email address")
+		}
+	}
+	errs.password("password", u.Password)
+	switch u.Role {
+	case roleAdmin, roleCoach:
+		if u.AthleteID != nil {
+			errs.add("athlete_id", "must be empty for staff accounts")
+		}
+	case roleAthlete, roleParent:
+		if u.AthleteID == nil || *u.AthleteID <= 0 {
+			errs.add("athlete_id", "is required for athlete and parent accounts")
+		}
+	default:
+		errs.add("role", "must be admin, coach, athlete or parent")
+	}
+	return errs
+}
+
+func (k *APIKey) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("name", k.Name) {
+		errs.maxLen("name", k.Name, 100)
+	}
+	if len(k.Scopes) == 0 {
+		errs.add("scopes", "must list at least one scope")
+	}
+	for _, s := range k.Scopes {
+		if !slices.Contains(apiKeyScopes, s) {
+			errs.add("scopes", "unknown scope %q; use %s", s, strings.Join(apiKeyScopes, ", "))
+		}
+	}
+	return errs
+}
+
+func (g *Goal) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if g.AthleteID <= 0 {
+		errs.add("athleteId", "is required")
+	}
+	if errs.required("label", g.Label) {
+		errs.maxLen("label", g.Label, 50)
+	}
+	if errs.required("target_time", g.TargetTime) {
+		errs.raceTime("target_time", g.TargetTime)
+	}
+	// Zero means the default 5K.
+	if g.Distance != 0 && (g.Distance < minDistance || g.Distance > maxDistance) {
+		errs.add("distance_m", "must be between %g and %g meters", minDistance, maxDistance)
+	}
+	if g.TargetMeetID != nil && *g.TargetMeetID <= 0 {
+		errs.add("targetMeetId", "must be a future meet ID")
+	}
+	return errs
+}
+
+// workout checks the fields workouts and plans share.
+func (v *ValidationErrors) workout(date, kind string, distance float64, duration, notes string) {
+	if v.required("date", date) {
+		v.date("date", date)
+	}
+	if !slices.Contains(workoutTypes, kind) {
+		v.add("type", "must be one of %s", strings.Join(workoutTypes, ", "))
+	}
+	if distance < 0 || distance > maxDistance {
+		v.add("distance_m", "must be between 0 and %g meters", maxDistance)
+	}
+	if duration != "" {
+		v.raceTime("duration", duration)
+	}
+	if distance == 0 && duration == "" {
+		v.add("distance_m", "distance_m or duration is required")
+	}
+	v.maxLen("notes", notes, 2000)
+}
+
+func (wo *Workout) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if wo.AthleteID <= 0 {
+		errs.add("athleteId", "is required")
+	}
+	errs.workout(wo.Date, wo.Type, wo.Distance, wo.Duration, wo.Notes)
+	return errs
+}
+
+func (p *WorkoutPlan) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if p.GroupID <= 0 {
+		errs.add("groupId", "is required")
+	}
+	errs.workout(p.Date, p.Type, p.Distance, p.Duration, p.Notes)
+	return errs
+}
+
+func (g *TrainingGroup) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("name", g.Name) {
+		errs.maxLen("name", g.Name, 50)
+	}
+	seen := map[int]bool{}
+	for i, id := range g.AthleteIDs {
+		if id <= 0 {
+			errs.add("athleteIds", "must be athlete IDs")
+			break
+		}
+		if seen[id] {
+			errs.add(fmt.Sprintf("athleteIds[%d]", i), "is listed more than once")
+		}
+		seen[id] = true
+	}
+	return errs
+}
+
+func (s *PracticeSession) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if errs.required("date", s.Date) {
+		errs.date("date", s.Date)
+	}
+	if errs.required("name", s.Name) {
+		errs.maxLen("name", s.Name, 100)
+	}
+	errs.maxLen("notes", s.Notes, 2000)
+	return errs
+}
+
+func (c *checkIn) Validate() ValidationErrors {
+	var errs ValidationErrors
+	seen := map[int]bool{}
+	for i, a := range c.Records {
+		field := fmt.Sprintf("records[%d].", i)
+		if a.AthleteID <= 0 {
+			errs.add(field+"athleteId", "is required")
+		} else if seen[a.AthleteID] {
+			errs.add(field+"athleteId", "is listed more than once")
+		}
+		seen[a.AthleteID] = true
+		if !slices.Contains(attendanceStatuses, a.Status) {
+			errs.add(field+"status", "must be one of %s", strings.Join(attendanceStatuses, ", "))
+		}
+		errs.maxLen(field+"note", a.Note, 200)
+	}
+	if c.Rest != "" && !slices.Contains(attendanceStatuses, c.Rest) {
+		errs.add("rest", "must be one of %s", strings.Join(attendanceStatuses, ", "))
+	}
+	if len(c.Records) == 0 && c.Rest == "" {
+		errs.add("records", "must list at least one athlete unless rest is set")
+	}
+	return errs
+}
+
+func (a *Availability) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if !slices.Contains(availabilityStatuses, a.Status) {
+		errs.add("status", "must be one of %s", strings.Join(availabilityStatuses, ", "))
+	}
+	if a.ReturnDate != "" {
+		if a.Status == availabilityHealthy {
+			errs.add("return_date", "only applies to a limited or out athlete")
+		} else {
+			errs.date("return_date", a.ReturnDate)
+		}
+	}
+	errs.maxLen("notes", a.Notes, 2000)
+	return errs
+}
+
+func (l *meetLineup) Validate() ValidationErrors {
+	var errs ValidationErrors
+	seen := map[int]bool{}
+	for i, e := range l.Entries {
+		field := fmt.Sprintf("entries[%d].athleteId", i)
+		if e.AthleteID <= 0 {
+			errs.add(field, "is required")
+		} else if seen[e.AthleteID] {
+			errs.add(field, "is listed more than once")
+		}
+		seen[e.AthleteID] = true
+	}
+	return errs
+}
+
+func (rules *SelectionRules) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if !slices.Contains(selectionMethods, rules.Method) {
+		errs.add("method", "must be one of %s", strings.Join(selectionMethods, ", "))
+	}
+	if rules.Races < 1 || rules.Races > 10 {
+		errs.add("races", "must be between 1 and 10")
+	}
+	if rules.MinAttendance < 0 || rules.MinAttendance > 100 {
+		errs.add("min_attendance", "must be between 0 and 100")
+	}
+	if rules.AttendanceWeeks < 1 || rules.AttendanceWeeks > 52 {
+		errs.add("attendance_weeks", "must be between 1 and 52")
+	}
+	if rules.VarsitySize < 1 || rules.VarsitySize > 20 {
+		errs.add("varsity_size", "must be between 1 and 20")
+	}
+	return errs
+}
+
+func (o *SelectionOverride) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if !slices.Contains(placements, o.Placement) {
+		errs.add("placement", "must be one of %s", strings.Join(placements, ", "))
+	}
+	errs.maxLen("note", o.Note, 200)
+	return errs
+}
+
+// maxSplits is far more checkpoints than any cross country course has.
+const maxSplits = 20
+
+func (rs *resultSplits) Validate() ValidationErrors {
+	var errs ValidationErrors
+	if len(rs.Splits) > maxSplits {
+		errs.add("splits", "must have at most %d checkpoints", maxSplits)
+		return errs
+	}
+	var prevDist float64
+	for i, s := range rs.Splits {
+		field := fmt.Sprintf("splits[%d].", i)
+		if errs.required(field+"label", s.Label) {
+			errs.maxLen(field+"label", s.Label, 50)
+		}
+		if s.Distance <= 0 {
+			errs.add(field+"distance_m", "must be greater than 0")
+		} else if s.Distance <= prevDist {
+			errs.add(field+"distance_m", "must be greater than the previous checkpoint's")
+		}
+		prevDist = max(prevDist, s.Distance)
+		// The order of the times is checked against the finish by
+		// checkSplitTimes.
+		if errs.required(field+"elapsed", s.Elapsed) {
+			errs.raceTime(field+"elapsed", s.Elapsed)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRaceTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"17:45", 1065},
+		{"17:45.3", 1065.3},
+		{"1:02:10", 3730},
+		{"0:59.99", 59.99},
+		{" 5:00 ", 300},
+		{"05:07", 307},
+		{"90:00", 5400},
+	}
+	for _, tt := range tests {
+		got, err := parseRaceTime(tt.in)
+		if err != nil {
+			t.Errorf("parseRaceTime(%q) error: %v", tt.in, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseRaceTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRaceTimeRejects(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"17",
+		"1:2:3:4",
+		"17:",
+		":45",
+		"17:60",
+		"1:60:00",
+		"-1:00",
+		"+5:00",
+		"5:+3",
+		"1e2:00",
+		"5:1e1",
+		"NaN:00",
+		"5:NaN",
+		"Inf:00",
+		"0x1:00",
+		"5.0:30",
+		"5:3.",
+		"5:.3",
+		"5:00.1.2",
+		"17 :45",
+		"１７:45",
+	} {
+		if got, err := parseRaceTime(in); err == nil {
+			t.Errorf("parseRaceTime(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestValidateRaceTime(t *testing.T) {
+	tests := []struct {
+		in    string
+		valid bool
+	}{
+		{"17:45", true},
+		{"17:45.3", true},
+		{"1:02:10", true},
+		{"17:60", false},
+		{"NaN:00", false},
+		{"fast", false},
+	}
+	for _, tt := range tests {
+		var errs ValidationErrors
+		errs.raceTime("time", tt.in)
+		if valid := len(errs) == 0; valid != tt.valid {
+			t.Errorf("raceTime(%q) valid = %v, want %v (%v)", tt.in, valid, tt.valid, errs)
+		}
+	}
+}
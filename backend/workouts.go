@@ -0,0 +1,745 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Training log ---
+//
+// Workouts are what an athlete actually ran; plans are what the coaches
+// assigned to a training group. Volume is summed per week (Monday to Sunday)
+// so the two can be compared. Everything here is staff-only apart from
+// /api/me/workouts, where athlete and parent accounts read their own log.
+
+var workoutTypes = []string{"easy", "long", "tempo", "intervals", "hills", "race", "recovery", "cross", "other"}
+
+// defaultTrainingWeeks is how far back reports look without ?from=.
+const defaultTrainingWeeks = 8
+
+type Workout struct {
+	ID        int       `json:"id"`
+	AthleteID int       `json:"athleteId"`
+	Date      string    `json:"date"`
+	Type      string    `json:"type"`
+	Distance  float64   `json:"distance_m,omitempty"`
+	Duration  string    `json:"duration,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	LoggedBy  string    `json:"logged_by,omitempty"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type TrainingGroup struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	AthleteIDs []int  `json:"athleteIds"`
+	Version    int    `json:"version"`
+}
+
+type WorkoutPlan struct {
+	ID       int     `json:"id"`
+	GroupID  int     `json:"groupId"`
+	Date     string  `json:"date"`
+	Type     string  `json:"type"`
+	Distance float64 `json:"distance_m,omitempty"`
+	Duration string  `json:"duration,omitempty"`
+	Notes    string  `json:"notes,omitempty"`
+	Version  int     `json:"version"`
+}
+
+const workoutColumns = "id, athlete_id, date, type, COALESCE(distance_m, 0), COALESCE(duration, ''), COALESCE(notes, ''), logged_by, version, created_at"
+
+func scanWorkout(row pgx.Row) (Workout, error) {
+	var wo Workout
+	var date time.Time
+	err := row.Scan(&wo.ID, &wo.AthleteID, &date, &wo.Type, &wo.Distance, &wo.Duration, &wo.Notes, &wo.LoggedBy, &wo.Version, &wo.CreatedAt)
+	wo.Date = date.Format("2006-01-02")
+	return wo, err
+}
+
+const planColumns = "id, group_id, date, type, COALESCE(distance_m, 0), COALESCE(duration, ''), COALESCE(notes, ''), version"
+
+func scanPlan(row pgx.Row) (WorkoutPlan, error) {
+	var p WorkoutPlan
+	var date time.Time
+	err := row.Scan(&p.ID, &p.GroupID, &date, &p.Type, &p.Distance, &p.Duration, &p.Notes, &p.Version)
+	p.Date = date.Format("2006-01-02")
+	return p, err
+}
+
+// dateRange reads ?from= and ?to= (YYYY-MM-DD, inclusive). They default to
+// the last defaultTrainingWeeks whole weeks through today. On failure it
+// writes a 400 and returns false.
+func dateRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+	to = time.Now().UTC().Truncate(24 * time.Hour)
+	if q.Has("to") {
+		var err error
+		if to, err = time.Parse("2006-01-02", q.Get("to")); err != nil {
+			writeError(w, r, errBadRequest("to must be in YYYY-MM-DD format"))
+			return from, to, false
+		}
+	}
+	from = weekStart(to).AddDate(0, 0, -7*(defaultTrainingWeeks-1))
+	if q.Has("from") {
+		var err error
+		if from, err = time.Parse("2006-01-02", q.Get("from")); err != nil {
+			writeError(w, r, errBadRequest("from must be in YYYY-MM-DD format"))
+			return from, to, false
+		}
+	}
+	if from.After(to) {
+		writeError(w, r, errBadRequest("from must not be after to"))
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// weekStart returns the Monday on or before d.
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+func metersToMiles(m float64) float64 {
+	return math.Round(m/metersPerMile*10) / 10
+}
+
+// WeekVolume totals one week of workouts.
+type WeekVolume struct {
+	WeekStart string  `json:"week_start"`
+	Workouts  int     `json:"workouts"`
+	Distance  float64 `json:"distance_m"`
+	Miles     float64 `json:"miles"`
+	Duration  string  `json:"duration,omitempty"`
+}
+
+// weeklyVolume sums workouts by week, oldest first.
+func weeklyVolume(workouts []Workout) []WeekVolume {
+	byWeek := map[string]*WeekVolume{}
+	seconds := map[string]float64{}
+	for _, wo := range workouts {
+		d, err := time.Parse("2006-01-02", wo.Date)
+		if err != nil {
+			continue
+		}
+		key := weekStart(d).Format("2006-01-02")
+		v := byWeek[key]
+		if v == nil {
+			v = &WeekVolume{WeekStart: key}
+			byWeek[key] = v
+		}
+		v.Workouts++
+		v.Distance += wo.Distance
+		if sec, err := parseRaceTime(wo.Duration); err == nil {
+			seconds[key] += sec
+		}
+	}
+
+	weeks := make([]WeekVolume, 0, len(byWeek))
+	for key, v := range byWeek {
+		v.Miles = metersToMiles(v.Distance)
+		if seconds[key] > 0 {
+			v.Duration = formatRaceTime(seconds[key])
+		}
+		weeks = append(weeks, *v)
+	}
+	slices.SortFunc(weeks, func(a, b WeekVolume) int { return cmp.Compare(a.WeekStart, b.WeekStart) })
+	return weeks
+}
+
+func loadWorkouts(ctx context.Context, athleteID int, from, to time.Time) ([]Workout, error) {
+	rows, err := db.Query(ctx,
+		"SELECT "+workoutColumns+" FROM workouts WHERE athlete_id = $1 AND date BETWEEN $2 AND $3 ORDER BY date DESC, id DESC",
+		athleteID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workouts := []Workout{}
+	for rows.Next() {
+		wo, err := scanWorkout(rows)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, wo)
+	}
+	return workouts, rows.Err()
+}
+
+// workoutsHandler manages the training log. Staff only.
+func workoutsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("id") {
+			id, ok := queryID(w, r)
+			if !ok {
+				return
+			}
+			wo, err := scanWorkout(db.QueryRow(ctx, "SELECT "+workoutColumns+" FROM workouts WHERE id = $1", id))
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, r, errNotFound("Workout not found"))
+				return
+			}
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			setETag(w, wo.Version)
+			json.NewEncoder(w).Encode(wo)
+			return
+		}
+		athleteID, err := strconv.Atoi(r.URL.Query().Get("athleteId"))
+		if err != nil {
+			writeError(w, r, errBadRequest("athleteId parameter required"))
+			return
+		}
+		from, to, ok := dateRange(w, r)
+		if !ok {
+			return
+		}
+		workouts, err := loadWorkouts(ctx, athleteID, from, to)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		json.NewEncoder(w).Encode(workouts)
+
+	case http.MethodPost:
+		var wo Workout
+		if !decodeValid(w, r, &wo) {
+			return
+		}
+		wo.LoggedBy = currentPrincipal(r).Username
+		err := db.QueryRow(ctx,
+			`INSERT INTO workouts (athlete_id, date, type, distance_m, duration, notes, logged_by)
+			 VALUES ($1, $2, $3, NULLIF($4::numeric, 0), NULLIF($5, ''), NULLIF($6, ''), $7) RETURNING id, version, created_at`,
+			wo.AthleteID, wo.Date, wo.Type, wo.Distance, wo.Duration, wo.Notes, wo.LoggedBy).Scan(&wo.ID, &wo.Version, &wo.CreatedAt)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, wo.Version)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(wo)
+
+	case http.MethodPut:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var wo Workout
+		if !decodeValid(w, r, &wo) {
+			return
+		}
+		wo, err := scanWorkout(db.QueryRow(ctx,
+			`UPDATE workouts SET date=$1, type=$2, distance_m=NULLIF($3::numeric, 0), duration=NULLIF($4, ''), notes=NULLIF($5, ''),
+			 version=version+1, updated_at=CURRENT_TIMESTAMP
+			 WHERE id=$6 AND ($7::int = 0 OR version=$7) RETURNING `+workoutColumns,
+			wo.Date, wo.Type, wo.Distance, wo.Duration, wo.Notes, id, expected))
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeMissedWrite(w, r, "workouts", id, expected, "Workout not found")
+			return
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, wo.Version)
+		json.NewEncoder(w).Encode(wo)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		tag, err := db.Exec(ctx, "DELETE FROM workouts WHERE id = $1 AND ($2::int = 0 OR version = $2)", id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "workouts", id, expected, "Workout not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// writeWeekly reports athleteID's weekly volume over the requested range.
+func writeWeekly(w http.ResponseWriter, r *http.Request, athleteID int) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return
+	}
+	workouts, err := loadWorkouts(ctx, athleteID, from, to)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(weeklyVolume(workouts))
+}
+
+// weeklyMileageHandler totals an athlete's workouts by week. Staff only.
+func weeklyMileageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	athleteID, err := strconv.Atoi(r.URL.Query().Get("athleteId"))
+	if err != nil {
+		writeError(w, r, errBadRequest("athleteId parameter required"))
+		return
+	}
+	writeWeekly(w, r, athleteID)
+}
+
+// meWorkoutsHandler lists the caller's athlete's workouts, or with
+// ?weekly=true their weekly totals.
+func meWorkoutsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	athleteID := currentPrincipal(r).AthleteID
+	if r.URL.Query().Get("weekly") == "true" {
+		writeWeekly(w, r, athleteID)
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return
+	}
+	workouts, err := loadWorkouts(ctx, athleteID, from, to)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	json.NewEncoder(w).Encode(workouts)
+}
+
+// --- Training groups and plans ---
+
+const groupSelect = `SELECT g.id, g.name, g.version,
+	COALESCE(array_agg(m.athlete_id ORDER BY m.athlete_id) FILTER (WHERE m.athlete_id IS NOT NULL), '{}')
+	FROM training_groups g LEFT JOIN training_group_members m ON m.group_id = g.id`
+
+func scanGroup(row pgx.Row) (TrainingGroup, error) {
+	var g TrainingGroup
+	err := row.Scan(&g.ID, &g.Name, &g.Version, &g.AthleteIDs)
+	return g, err
+}
+
+// setGroupMembers replaces a group's members. An athlete belongs to at most
+// one group, so adding one here moves them out of any other, and those
+// groups get a new version so a stale copy can't put them back. The group
+// itself was already bumped by the caller's write.
+func setGroupMembers(ctx context.Context, tx pgx.Tx, groupID int, athleteIDs []int) error {
+	if _, err := tx.Exec(ctx,
+		`UPDATE training_groups SET version = version + 1, updated_at = CURRENT_TIMESTAMP
+		 WHERE id IN (SELECT group_id FROM training_group_members WHERE athlete_id = ANY($2) AND group_id <> $1)`,
+		groupID, athleteIDs); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM training_group_members WHERE group_id = $1 OR athlete_id = ANY($2)", groupID, athleteIDs); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx,
+		"INSERT INTO training_group_members (group_id, athlete_id) SELECT $1, unnest($2::int[])", groupID, athleteIDs)
+	return err
+}
+
+// trainingGroupsHandler manages training groups and their members. Staff
+// only.
+func trainingGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("id") {
+			id, ok := queryID(w, r)
+			if !ok {
+				return
+			}
+			g, err := scanGroup(db.QueryRow(ctx, groupSelect+" WHERE g.id = $1 GROUP BY g.id", id))
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, r, errNotFound("Training group not found"))
+				return
+			}
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			setETag(w, g.Version)
+			json.NewEncoder(w).Encode(g)
+			return
+		}
+		rows, err := db.Query(ctx, groupSelect+" GROUP BY g.id ORDER BY g.name")
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		groups := []TrainingGroup{}
+		for rows.Next() {
+			g, err := scanGroup(rows)
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			groups = append(groups, g)
+		}
+		json.NewEncoder(w).Encode(groups)
+
+	case http.MethodPost, http.MethodPut:
+		var id, expected int
+		if r.Method == http.MethodPut {
+			var ok bool
+			if id, ok = queryID(w, r); !ok {
+				return
+			}
+			if expected, ok = ifMatchVersion(w, r); !ok {
+				return
+			}
+		}
+		var g TrainingGroup
+		if !decodeValid(w, r, &g) {
+			return
+		}
+		if g.AthleteIDs == nil {
+			g.AthleteIDs = []int{}
+		}
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		if r.Method == http.MethodPost {
+			err = tx.QueryRow(ctx, "INSERT INTO training_groups (name) VALUES ($1) RETURNING id, version", g.Name).Scan(&g.ID, &g.Version)
+		} else {
+			err = tx.QueryRow(ctx,
+				`UPDATE training_groups SET name=$1, version=version+1, updated_at=CURRENT_TIMESTAMP
+				 WHERE id=$2 AND ($3::int = 0 OR version=$3) RETURNING id, version`,
+				g.Name, id, expected).Scan(&g.ID, &g.Version)
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeMissedWrite(w, r, "training_groups", id, expected, "Training group not found")
+				return
+			}
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if err := setGroupMembers(ctx, tx, g.ID, g.AthleteIDs); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		slices.Sort(g.AthleteIDs)
+		setETag(w, g.Version)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(g)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		tag, err := db.Exec(ctx, "DELETE FROM training_groups WHERE id = $1 AND ($2::int = 0 OR version = $2)", id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "training_groups", id, expected, "Training group not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// workoutPlansHandler manages the workouts assigned to training groups.
+// Staff only.
+func workoutPlansHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("id") {
+			id, ok := queryID(w, r)
+			if !ok {
+				return
+			}
+			p, err := scanPlan(db.QueryRow(ctx, "SELECT "+planColumns+" FROM workout_plans WHERE id = $1", id))
+			if errors.Is(err, pgx.ErrNoRows) {
+				writeError(w, r, errNotFound("Workout plan not found"))
+				return
+			}
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			setETag(w, p.Version)
+			json.NewEncoder(w).Encode(p)
+			return
+		}
+		groupID, err := strconv.Atoi(r.URL.Query().Get("groupId"))
+		if err != nil {
+			writeError(w, r, errBadRequest("groupId parameter required"))
+			return
+		}
+		from, to, ok := dateRange(w, r)
+		if !ok {
+			return
+		}
+		rows, err := db.Query(ctx,
+			"SELECT "+planColumns+" FROM workout_plans WHERE group_id = $1 AND date BETWEEN $2 AND $3 ORDER BY date, id",
+			groupID, from, to)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		plans := []WorkoutPlan{}
+		for rows.Next() {
+			p, err := scanPlan(rows)
+			if err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			plans = append(plans, p)
+		}
+		json.NewEncoder(w).Encode(plans)
+
+	case http.MethodPost:
+		var p WorkoutPlan
+		if !decodeValid(w, r, &p) {
+			return
+		}
+		err := db.QueryRow(ctx,
+			`INSERT INTO workout_plans (group_id, date, type, distance_m, duration, notes)
+			 VALUES ($1, $2, $3, NULLIF($4::numeric, 0), NULLIF($5, ''), NULLIF($6, '')) RETURNING id, version`,
+			p.GroupID, p.Date, p.Type, p.Distance, p.Duration, p.Notes).Scan(&p.ID, &p.Version)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, p.Version)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(p)
+
+	case http.MethodPut:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		var p WorkoutPlan
+		if !decodeValid(w, r, &p) {
+			return
+		}
+		p, err := scanPlan(db.QueryRow(ctx,
+			`UPDATE workout_plans SET group_id=$1, date=$2, type=$3, distance_m=NULLIF($4::numeric, 0), duration=NULLIF($5, ''),
+			 notes=NULLIF($6, ''), version=version+1, updated_at=CURRENT_TIMESTAMP
+			 WHERE id=$7 AND ($8::int = 0 OR version=$8) RETURNING `+planColumns,
+			p.GroupID, p.Date, p.Type, p.Distance, p.Duration, p.Notes, id, expected))
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeMissedWrite(w, r, "workout_plans", id, expected, "Workout plan not found")
+			return
+		}
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		setETag(w, p.Version)
+		json.NewEncoder(w).Encode(p)
+
+	case http.MethodDelete:
+		id, ok := queryID(w, r)
+		if !ok {
+			return
+		}
+		expected, ok := ifMatchVersion(w, r)
+		if !ok {
+			return
+		}
+		tag, err := db.Exec(ctx, "DELETE FROM workout_plans WHERE id = $1 AND ($2::int = 0 OR version = $2)", id, expected)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		if tag.RowsAffected() == 0 {
+			writeMissedWrite(w, r, "workout_plans", id, expected, "Workout plan not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, errMethodNotAllowed())
+	}
+}
+
+// VolumeComparison sets planned against actual volume, either for one week of
+// one athlete or for one athlete over the whole range.
+type VolumeComparison struct {
+	WeekStart    string   `json:"week_start,omitempty"`
+	AthleteID    int      `json:"athleteId,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Planned      float64  `json:"planned_m"`
+	Actual       float64  `json:"actual_m"`
+	PlannedMiles float64  `json:"planned_miles"`
+	ActualMiles  float64  `json:"actual_miles"`
+	Percent      *float64 `json:"percent"`
+}
+
+func (v *VolumeComparison) finish() {
+	v.PlannedMiles = metersToMiles(v.Planned)
+	v.ActualMiles = metersToMiles(v.Actual)
+	if v.Planned > 0 {
+		pct := math.Round(v.Actual / v.Planned * 100)
+		v.Percent = &pct
+	}
+}
+
+// volumeCompareHandler compares planned with actual volume: week by week
+// for ?athleteId=, or athlete by athlete over the range for ?groupId=. An
+// athlete's plan is their group's. Staff only.
+func volumeCompareHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		writeError(w, r, errMethodNotAllowed())
+		return
+	}
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+
+	if q.Has("athleteId") {
+		athleteID, err := strconv.Atoi(q.Get("athleteId"))
+		if err != nil {
+			writeError(w, r, errBadRequest("Invalid athleteId format"))
+			return
+		}
+		rows, err := db.Query(ctx,
+			`SELECT week, sum(planned), sum(actual) FROM (
+			     SELECT date_trunc('week', p.date)::date AS week, COALESCE(p.distance_m, 0) AS planned, 0 AS actual
+			     FROM workout_plans p JOIN training_group_members m ON m.group_id = p.group_id
+			     WHERE m.athlete_id = $1 AND p.date BETWEEN $2 AND $3
+			     UNION ALL
+			     SELECT date_trunc('week', date)::date, 0, COALESCE(distance_m, 0)
+			     FROM workouts WHERE athlete_id = $1 AND date BETWEEN $2 AND $3
+			 ) v GROUP BY week ORDER BY week`,
+			athleteID, from, to)
+		if err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		weeks := []VolumeComparison{}
+		for rows.Next() {
+			var v VolumeComparison
+			var week time.Time
+			if err := rows.Scan(&week, &v.Planned, &v.Actual); err != nil {
+				writeDBError(w, r, err)
+				return
+			}
+			v.WeekStart = week.Format("2006-01-02")
+			v.finish()
+			weeks = append(weeks, v)
+		}
+		json.NewEncoder(w).Encode(weeks)
+		return
+	}
+
+	groupID, err := strconv.Atoi(q.Get("groupId"))
+	if err != nil {
+		writeError(w, r, errBadRequest("athleteId or groupId parameter required"))
+		return
+	}
+	rows, err := db.Query(ctx,
+		`SELECT a.id, a.name,
+		     (SELECT COALESCE(sum(distance_m), 0) FROM workout_plans WHERE group_id = $1 AND date BETWEEN $2 AND $3),
+		     (SELECT COALESCE(sum(distance_m), 0) FROM workouts WHERE athlete_id = a.id AND date BETWEEN $2 AND $3)
+		 FROM training_group_members m JOIN athletes a ON a.id = m.athlete_id
+		 WHERE m.group_id = $1 ORDER BY a.name`,
+		groupID, from, to)
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	athletes := []VolumeComparison{}
+	for rows.Next() {
+		var v VolumeComparison
+		if err := rows.Scan(&v.AthleteID, &v.Name, &v.Planned, &v.Actual); err != nil {
+			writeDBError(w, r, err)
+			return
+		}
+		v.finish()
+		athletes = append(athletes, v)
+	}
+	json.NewEncoder(w).Encode(athletes)
+}
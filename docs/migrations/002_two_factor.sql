@@ -0,0 +1,23 @@
+-- Adds optional TOTP two-factor authentication.
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    username VARCHAR(100) PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One-time recovery codes (SHA-256 hashes)
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL REFERENCES user_totp(username) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_totp_recovery_codes_username ON totp_recovery_codes(username);
+
+GRANT ALL ON user_totp TO xc_app;
+GRANT ALL ON totp_recovery_codes TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE totp_recovery_codes_id_seq TO xc_app;
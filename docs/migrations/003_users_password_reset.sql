@@ -0,0 +1,32 @@
+-- Adds staff accounts and password reset tokens.
+
+-- Staff accounts (the configured admin needs no row)
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'coach' CHECK (role IN ('admin', 'coach')),
+    -- Tokens issued before this are rejected (set on password reset)
+    tokens_valid_after TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Single-use password reset tokens (SHA-256 hashes)
+CREATE TABLE IF NOT EXISTS password_resets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(lower(email));
+CREATE INDEX IF NOT EXISTS idx_password_resets_user ON password_resets(user_id);
+
+GRANT ALL ON users TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE users_id_seq TO xc_app;
+GRANT ALL ON password_resets TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE password_resets_id_seq TO xc_app;
@@ -0,0 +1,22 @@
+-- Adds read-only athlete and parent accounts and private coach notes.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS athlete_id INTEGER REFERENCES athletes(id) ON DELETE CASCADE;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'coach', 'athlete', 'parent'));
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_athlete_scope;
+ALTER TABLE users ADD CONSTRAINT users_athlete_scope CHECK ((role IN ('athlete', 'parent')) = (athlete_id IS NOT NULL));
+
+CREATE TABLE IF NOT EXISTS athlete_notes (
+    id SERIAL PRIMARY KEY,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    author VARCHAR(100) NOT NULL,
+    body TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_athlete_notes_athlete ON athlete_notes(athlete_id);
+
+GRANT ALL ON athlete_notes TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE athlete_notes_id_seq TO xc_app;
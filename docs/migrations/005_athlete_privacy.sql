@@ -0,0 +1,4 @@
+-- Adds per-athlete privacy for the public API.
+
+ALTER TABLE athletes ADD COLUMN IF NOT EXISTS privacy VARCHAR(10) NOT NULL DEFAULT 'full'
+    CHECK (privacy IN ('full', 'initials', 'hidden'));
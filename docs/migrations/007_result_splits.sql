@@ -0,0 +1,14 @@
+-- Adds per-checkpoint splits to results.
+
+CREATE TABLE IF NOT EXISTS result_splits (
+    id SERIAL PRIMARY KEY,
+    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL,
+    distance_m NUMERIC(7, 2) NOT NULL CHECK (distance_m > 0),
+    elapsed VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(result_id, distance_m)
+);
+
+GRANT ALL ON result_splits TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE result_splits_id_seq TO xc_app;
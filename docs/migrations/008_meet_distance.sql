@@ -0,0 +1,3 @@
+-- Adds race distances to meets so results can report pace.
+
+ALTER TABLE meets ADD COLUMN IF NOT EXISTS distance_m NUMERIC(7, 2) CHECK (distance_m > 0);
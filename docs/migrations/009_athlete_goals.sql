@@ -0,0 +1,18 @@
+-- Adds athlete goal times.
+
+CREATE TABLE IF NOT EXISTS athlete_goals (
+    id SERIAL PRIMARY KEY,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL,
+    target_time VARCHAR(20) NOT NULL,
+    distance_m NUMERIC(7, 2) NOT NULL DEFAULT 5000 CHECK (distance_m > 0),
+    target_meet_id INTEGER REFERENCES future_meets(id) ON DELETE SET NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_athlete_goals_athlete ON athlete_goals(athlete_id);
+
+GRANT ALL ON athlete_goals TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE athlete_goals_id_seq TO xc_app;
@@ -0,0 +1,50 @@
+-- Adds the training log, training groups and workout plans.
+
+CREATE TABLE IF NOT EXISTS workouts (
+    id SERIAL PRIMARY KEY,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    distance_m NUMERIC(8, 2) CHECK (distance_m > 0),
+    duration VARCHAR(20),
+    notes TEXT,
+    logged_by VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Training groups; an athlete is in at most one
+CREATE TABLE IF NOT EXISTS training_groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS training_group_members (
+    group_id INTEGER NOT NULL REFERENCES training_groups(id) ON DELETE CASCADE,
+    athlete_id INTEGER NOT NULL UNIQUE REFERENCES athletes(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, athlete_id)
+);
+
+-- Workouts coaches assign to a training group
+CREATE TABLE IF NOT EXISTS workout_plans (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES training_groups(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    distance_m NUMERIC(8, 2) CHECK (distance_m > 0),
+    duration VARCHAR(20),
+    notes TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workouts_athlete_date ON workouts(athlete_id, date);
+CREATE INDEX IF NOT EXISTS idx_workout_plans_group_date ON workout_plans(group_id, date);
+
+GRANT ALL ON workouts, training_groups, training_group_members, workout_plans TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE workouts_id_seq, training_groups_id_seq, workout_plans_id_seq TO xc_app;
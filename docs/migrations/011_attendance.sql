@@ -0,0 +1,27 @@
+-- Adds practice sessions and attendance.
+
+CREATE TABLE IF NOT EXISTS practice_sessions (
+    id SERIAL PRIMARY KEY,
+    date DATE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    notes TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS attendance (
+    session_id INTEGER NOT NULL REFERENCES practice_sessions(id) ON DELETE CASCADE,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('present', 'excused', 'absent', 'injured')),
+    note VARCHAR(200),
+    recorded_by VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, athlete_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_practice_sessions_date ON practice_sessions(date);
+CREATE INDEX IF NOT EXISTS idx_attendance_athlete ON attendance(athlete_id);
+
+GRANT ALL ON practice_sessions, attendance TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE practice_sessions_id_seq TO xc_app;
@@ -0,0 +1,13 @@
+-- Adds injury and availability status for athletes.
+
+CREATE TABLE IF NOT EXISTS athlete_availability (
+    athlete_id INTEGER PRIMARY KEY REFERENCES athletes(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('healthy', 'limited', 'out')),
+    return_date DATE,
+    notes TEXT,
+    updated_by VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+GRANT ALL ON athlete_availability TO xc_app;
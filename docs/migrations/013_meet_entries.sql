@@ -0,0 +1,15 @@
+-- Adds meet entries for upcoming meets.
+
+CREATE TABLE IF NOT EXISTS meet_entries (
+    id SERIAL PRIMARY KEY,
+    future_meet_id INTEGER NOT NULL REFERENCES future_meets(id) ON DELETE CASCADE,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    alternate BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (future_meet_id, athlete_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_meet_entries_athlete ON meet_entries(athlete_id);
+
+GRANT ALL ON meet_entries TO xc_app;
+GRANT USAGE, SELECT ON SEQUENCE meet_entries_id_seq TO xc_app;
@@ -0,0 +1,23 @@
+-- Adds varsity selection rules and coach overrides.
+
+CREATE TABLE IF NOT EXISTS selection_rules (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    method VARCHAR(10) NOT NULL CHECK (method IN ('recent', 'season')),
+    races INTEGER NOT NULL,
+    min_attendance NUMERIC(5,2) NOT NULL DEFAULT 0,
+    attendance_weeks INTEGER NOT NULL,
+    varsity_size INTEGER NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS selection_overrides (
+    athlete_id INTEGER PRIMARY KEY REFERENCES athletes(id) ON DELETE CASCADE,
+    placement VARCHAR(10) NOT NULL CHECK (placement IN ('varsity', 'jv')),
+    note VARCHAR(200),
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+GRANT ALL ON selection_rules, selection_overrides TO xc_app;
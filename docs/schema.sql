@@ -8,7 +8,11 @@ CREATE TABLE athletes (
     grade INTEGER CHECK (grade BETWEEN 9 AND 12),
     personal_record VARCHAR(20),
     events VARCHAR(200),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    -- What the public API shows: full name, initials only, or nothing
+    privacy VARCHAR(10) NOT NULL DEFAULT 'full' CHECK (privacy IN ('full', 'initials', 'hidden')),
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Meets table
@@ -18,7 +22,11 @@ CREATE TABLE meets (
     date DATE NOT NULL,
     location VARCHAR(100),
     description TEXT,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    -- Race distance in meters; NULL when unknown
+    distance_m NUMERIC(7, 2) CHECK (distance_m > 0),
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Results table
@@ -28,7 +36,9 @@ CREATE TABLE results (
     meet_id INTEGER REFERENCES meets(id) ON DELETE CASCADE,
     time VARCHAR(20) NOT NULL,
     place INTEGER,
+    version INTEGER NOT NULL DEFAULT 1,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     UNIQUE(athlete_id, meet_id)
 );
 
@@ -38,7 +48,9 @@ CREATE TABLE coaches (
     name VARCHAR(100) NOT NULL,
     title VARCHAR(100) NOT NULL,
     bio TEXT,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Future meets table
@@ -48,6 +60,206 @@ CREATE TABLE future_meets (
     date DATE NOT NULL,
     location VARCHAR(100),
     level VARCHAR(20) NOT NULL DEFAULT 'Varsity',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Two-factor authentication (TOTP) per login
+CREATE TABLE user_totp (
+    username VARCHAR(100) PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One-time recovery codes (SHA-256 hashes)
+CREATE TABLE totp_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL REFERENCES user_totp(username) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- Accounts (the configured admin needs no row). Athlete and parent accounts
+-- are read-only and scoped to one athlete.
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'coach' CHECK (role IN ('admin', 'coach', 'athlete', 'parent')),
+    athlete_id INTEGER REFERENCES athletes(id) ON DELETE CASCADE,
+    -- Tokens issued before this are rejected (set on password reset)
+    tokens_valid_after TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT users_athlete_scope CHECK ((role IN ('athlete', 'parent')) = (athlete_id IS NOT NULL))
+);
+
+-- Single-use password reset tokens (SHA-256 hashes)
+CREATE TABLE password_resets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Private coach notes, visible to staff and the athlete's own accounts
+CREATE TABLE athlete_notes (
+    id SERIAL PRIMARY KEY,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    author VARCHAR(100) NOT NULL,
+    body TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- API keys for integrations (SHA-256 hashes; the key is shown once)
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+-- Checkpoint times within a result (e.g. the 1-mile and 2-mile marks)
+CREATE TABLE result_splits (
+    id SERIAL PRIMARY KEY,
+    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL,
+    distance_m NUMERIC(7, 2) NOT NULL CHECK (distance_m > 0),
+    elapsed VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(result_id, distance_m)
+);
+
+-- Goal times set by coaches, optionally for a scheduled meet
+CREATE TABLE athlete_goals (
+    id SERIAL PRIMARY KEY,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL,
+    target_time VARCHAR(20) NOT NULL,
+    distance_m NUMERIC(7, 2) NOT NULL DEFAULT 5000 CHECK (distance_m > 0),
+    target_meet_id INTEGER REFERENCES future_meets(id) ON DELETE SET NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Training log: workouts each athlete actually ran
+CREATE TABLE workouts (
+    id SERIAL PRIMARY KEY,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    distance_m NUMERIC(8, 2) CHECK (distance_m > 0),
+    duration VARCHAR(20),
+    notes TEXT,
+    logged_by VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Training groups; an athlete is in at most one
+CREATE TABLE training_groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE training_group_members (
+    group_id INTEGER NOT NULL REFERENCES training_groups(id) ON DELETE CASCADE,
+    athlete_id INTEGER NOT NULL UNIQUE REFERENCES athletes(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, athlete_id)
+);
+
+-- Workouts coaches assign to a training group
+CREATE TABLE workout_plans (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES training_groups(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    distance_m NUMERIC(8, 2) CHECK (distance_m > 0),
+    duration VARCHAR(20),
+    notes TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Practice sessions and per-athlete attendance
+CREATE TABLE practice_sessions (
+    id SERIAL PRIMARY KEY,
+    date DATE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    notes TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE attendance (
+    session_id INTEGER NOT NULL REFERENCES practice_sessions(id) ON DELETE CASCADE,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('present', 'excused', 'absent', 'injured')),
+    note VARCHAR(200),
+    recorded_by VARCHAR(100) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, athlete_id)
+);
+
+-- Injury and availability status; no row means healthy
+CREATE TABLE athlete_availability (
+    athlete_id INTEGER PRIMARY KEY REFERENCES athletes(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('healthy', 'limited', 'out')),
+    return_date DATE,
+    notes TEXT,
+    updated_by VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Athletes entered in upcoming meets
+CREATE TABLE meet_entries (
+    id SERIAL PRIMARY KEY,
+    future_meet_id INTEGER NOT NULL REFERENCES future_meets(id) ON DELETE CASCADE,
+    athlete_id INTEGER NOT NULL REFERENCES athletes(id) ON DELETE CASCADE,
+    alternate BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (future_meet_id, athlete_id)
+);
+
+-- Varsity selection rules (a single row) and coach overrides
+CREATE TABLE selection_rules (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    method VARCHAR(10) NOT NULL CHECK (method IN ('recent', 'season')),
+    races INTEGER NOT NULL,
+    min_attendance NUMERIC(5,2) NOT NULL DEFAULT 0,
+    attendance_weeks INTEGER NOT NULL,
+    varsity_size INTEGER NOT NULL,
+    updated_by VARCHAR(100) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE selection_overrides (
+    athlete_id INTEGER PRIMARY KEY REFERENCES athletes(id) ON DELETE CASCADE,
+    placement VARCHAR(10) NOT NULL CHECK (placement IN ('varsity', 'jv')),
+    note VARCHAR(200),
+    created_by VARCHAR(100) NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -55,3 +267,13 @@ CREATE TABLE future_meets (
 CREATE INDEX idx_results_athlete ON results(athlete_id);
 CREATE INDEX idx_results_meet ON results(meet_id);
 CREATE INDEX idx_meets_date ON meets(date);
+CREATE INDEX idx_totp_recovery_codes_username ON totp_recovery_codes(username);
+CREATE UNIQUE INDEX idx_users_email ON users(lower(email));
+CREATE INDEX idx_password_resets_user ON password_resets(user_id);
+CREATE INDEX idx_athlete_notes_athlete ON athlete_notes(athlete_id);
+CREATE INDEX idx_athlete_goals_athlete ON athlete_goals(athlete_id);
+CREATE INDEX idx_workouts_athlete_date ON workouts(athlete_id, date);
+CREATE INDEX idx_workout_plans_group_date ON workout_plans(group_id, date);
+CREATE INDEX idx_practice_sessions_date ON practice_sessions(date);
+CREATE INDEX idx_attendance_athlete ON attendance(athlete_id);
+CREATE INDEX idx_meet_entries_athlete ON meet_entries(athlete_id);
@@ -9,7 +9,9 @@ import MeetDetail from './pages/MeetDetail'
 import Rankings from './pages/Rankings'
 import Coaches from './pages/Coaches'
 import Login from './pages/Login'
+import ResetPassword from './pages/ResetPassword'
 import Admin from './pages/Admin'
+import MyAthlete from './pages/MyAthlete'
 
 export default function App() {
   return (
@@ -23,9 +25,13 @@ export default function App() {
           <Route path="rankings" element={<Rankings />} />
           <Route path="coaches" element={<Coaches />} />
           <Route path="login" element={<Login />} />
+          <Route path="reset-password" element={<ResetPassword />} />
           <Route element={<PrivateRoute />}>
             <Route path="admin" element={<Admin />} />
           </Route>
+          <Route element={<PrivateRoute allowAthletes />}>
+            <Route path="me" element={<MyAthlete />} />
+          </Route>
         </Route>
       </Routes>
     </AuthProvider>
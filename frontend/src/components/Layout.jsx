@@ -29,7 +29,7 @@ function mobileLinkClass({ isActive }) {
 }
 
 export default function Layout() {
-  const { isAdmin, logout } = useAuth()
+  const { user, isAdmin, logout } = useAuth()
   const [menuOpen, setMenuOpen] = useState(false)
   const location = useLocation()
 
@@ -87,9 +87,11 @@ export default function Layout() {
                     <span aria-hidden="true">{icon} </span>{label}
                   </NavLink>
                 ))}
-                {isAdmin ? (
+                {user ? (
                   <>
-                    <NavLink to="/admin" className={linkClass}><span aria-hidden="true">⚙️ </span>Admin</NavLink>
+                    {isAdmin
+                      ? <NavLink to="/admin" className={linkClass}><span aria-hidden="true">⚙️ </span>Admin</NavLink>
+                      : <NavLink to="/me" className={linkClass}><span aria-hidden="true">👤 </span>My Page</NavLink>}
                     <button
                       onClick={logout}
                       className="ml-2 px-3 py-1 rounded bg-[#FFD700] text-[#4D007B] text-sm font-semibold hover:bg-[#e6c200] transition-colors"
@@ -136,9 +138,11 @@ export default function Layout() {
                 <span aria-hidden="true">{icon} </span>{label}
               </NavLink>
             ))}
-            {isAdmin ? (
+            {user ? (
               <>
-                <NavLink to="/admin" className={mobileLinkClass} onClick={() => setMenuOpen(false)}><span aria-hidden="true">⚙️ </span>Admin</NavLink>
+                {isAdmin
+                  ? <NavLink to="/admin" className={mobileLinkClass} onClick={() => setMenuOpen(false)}><span aria-hidden="true">⚙️ </span>Admin</NavLink>
+                  : <NavLink to="/me" className={mobileLinkClass} onClick={() => setMenuOpen(false)}><span aria-hidden="true">👤 </span>My Page</NavLink>}
                 <button
                   onClick={() => { logout(); setMenuOpen(false) }}
                   className="mt-2 px-4 py-2 rounded bg-[#FFD700] text-[#4D007B] text-sm font-semibold"
@@ -1,7 +1,8 @@
 import { Navigate, Outlet } from 'react-router-dom'
 import { useAuth } from '../context/AuthContext'
 
-export default function PrivateRoute() {
-  const { isAdmin } = useAuth()
-  return isAdmin ? <Outlet /> : <Navigate to="/login" replace />
+// Staff-only unless allowAthletes, which admits any signed-in account.
+export default function PrivateRoute({ allowAthletes = false }) {
+  const { user, isAdmin } = useAuth()
+  return isAdmin || (allowAthletes && user) ? <Outlet /> : <Navigate to="/login" replace />
 }
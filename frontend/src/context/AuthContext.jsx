@@ -2,28 +2,51 @@ import { createContext, useContext, useState, useEffect } from 'react'
 
 const AuthContext = createContext(null)
 
+// decodeClaims reads a JWT's payload without verifying it; the server checks
+// the signature, this only restores the session and spots expired tokens.
+function decodeClaims(token) {
+  const parts = token.split('.')
+  if (parts.length !== 3) return null
+  try {
+    const json = atob(parts[1].replace(/-/g, '+').replace(/_/g, '/'))
+    return JSON.parse(json)
+  } catch {
+    return null
+  }
+}
+
+// Athlete and parent accounts are read-only; only staff get the admin pages.
+function isStaff(role) {
+  return role === 'admin' || role === 'coach'
+}
+
 export function AuthProvider({ children }) {
   const [user, setUser] = useState(null)
   const [token, setToken] = useState(null)
+  const [role, setRole] = useState(null)
 
   useEffect(() => {
     const stored = sessionStorage.getItem('xc_token')
     if (stored) {
-      const parts = stored.split('.')
-      if (parts.length === 2) {
-        const colonIdx = parts[0].lastIndexOf(':')
-        if (colonIdx > 0) {
-          const expiry = parseInt(parts[0].slice(colonIdx + 1), 10)
-          if (Date.now() / 1000 < expiry) {
-            const username = parts[0].slice(0, colonIdx)
-            setUser(username)
-            setToken(stored)
-            return
-          }
-        }
+      const claims = decodeClaims(stored)
+      if (claims && Date.now() / 1000 < claims.exp) {
+        setUser(claims.sub)
+        setRole(claims.role)
+        setToken(stored)
+        return
       }
       sessionStorage.removeItem('xc_token')
     }
+    // In cookie mode the session is invisible to script; ask the server.
+    fetch('/api/session')
+      .then((res) => (res.ok ? res.json() : null))
+      .then((data) => {
+        if (data) {
+          setUser(data.username)
+          setRole(data.role)
+        }
+      })
+      .catch(() => {})
   }, [])
 
   async function login(username, password) {
@@ -36,25 +59,60 @@ export function AuthProvider({ children }) {
       const data = await res.json()
       throw new Error(data.error || 'Login failed')
     }
-    const { token: t } = await res.json()
-    sessionStorage.setItem('xc_token', t)
-    setToken(t)
+    const data = await res.json()
+    // Accounts with two-factor auth get a short-lived MFA token instead.
+    if (data.mfa_required) return { mfaToken: data.mfa_token }
+    return { role: storeSession(data, username) }
+  }
+
+  async function verifyMfa(mfaToken, username, { code, recoveryCode }) {
+    const res = await fetch('/api/login/2fa', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ mfa_token: mfaToken, code, recovery_code: recoveryCode }),
+    })
+    if (!res.ok) {
+      const data = await res.json()
+      throw new Error(data.error || 'Verification failed')
+    }
+    return { role: storeSession(await res.json(), username) }
+  }
+
+  // Cookie-mode logins return no token; the browser holds the session.
+  function storeSession(data, username) {
+    let r = data.role
+    if (data.token) {
+      sessionStorage.setItem('xc_token', data.token)
+      setToken(data.token)
+      r = decodeClaims(data.token)?.role
+    }
     setUser(username)
+    setRole(r)
+    return r
   }
 
   function logout() {
+    fetch('/api/logout', { method: 'POST', headers: csrfHeaders() }).catch(() => {})
     sessionStorage.removeItem('xc_token')
     setToken(null)
     setUser(null)
+    setRole(null)
   }
 
   return (
-    <AuthContext.Provider value={{ user, token, login, logout, isAdmin: user !== null }}>
+    <AuthContext.Provider value={{ user, role, token, login, verifyMfa, logout, isAdmin: isStaff(role) }}>
       {children}
     </AuthContext.Provider>
   )
 }
 
+// csrfHeaders echoes the CSRF cookie set by a cookie-mode login, which the
+// server requires on every write made with the session cookie.
+export function csrfHeaders() {
+  const match = document.cookie.match(/(?:^|;\s*)xc_csrf=([^;]+)/)
+  return match ? { 'X-CSRF-Token': match[1] } : {}
+}
+
 export function useAuth() {
   return useContext(AuthContext)
 }
@@ -1,17 +1,19 @@
-import { useAuth } from '../context/AuthContext'
+import { useAuth, csrfHeaders } from '../context/AuthContext'
 
 export function useApi() {
   const { token } = useAuth()
 
-  function authHeaders(hasBody) {
-    const h = {}
+  function authHeaders(hasBody, version) {
+    const h = csrfHeaders()
     if (hasBody) h['Content-Type'] = 'application/json'
     if (token) h['Authorization'] = `Bearer ${token}`
+    // Send the version we loaded so the server rejects stale edits with 412.
+    if (version) h['If-Match'] = `"${version}"`
     return h
   }
 
   async function get(url) {
-    const res = await fetch(url)
+    const res = await fetch(url, { headers: token ? { Authorization: `Bearer ${token}` } : {} })
     if (!res.ok) throw new Error(`GET ${url} failed: ${res.status}`)
     return res.json()
   }
@@ -29,10 +31,10 @@ export function useApi() {
     return res.json()
   }
 
-  async function put(url, body) {
+  async function put(url, body, version) {
     const res = await fetch(url, {
       method: 'PUT',
-      headers: authHeaders(true),
+      headers: authHeaders(true, version),
       body: JSON.stringify(body),
     })
     if (!res.ok) {
@@ -42,10 +44,10 @@ export function useApi() {
     return res.json()
   }
 
-  async function del(url) {
+  async function del(url, version) {
     const res = await fetch(url, {
       method: 'DELETE',
-      headers: authHeaders(false),
+      headers: authHeaders(false, version),
     })
     if (!res.ok) {
       const text = await res.text()
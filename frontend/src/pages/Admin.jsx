@@ -51,7 +51,7 @@ function TabBar({ active, onChange }) {
 // ─── Athletes tab ──────────────────────────────────────────────
 
 function emptyAthlete() {
-  return { name: '', gender: 'M', grade: 9, personal_record: '', events: '' }
+  return { name: '', gender: 'M', grade: 9, personal_record: '', events: '', privacy: 'full' }
 }
 
 function AthleteForm({ initial, onSave, onCancel }) {
@@ -80,6 +80,13 @@ function AthleteForm({ initial, onSave, onCancel }) {
       <td className="px-3 py-2">
         <input aria-label="Events" value={form.events} onChange={set('events')} placeholder="e.g. 5K Varsity" className="w-full border border-gray-300 rounded px-2 py-1 text-sm focus:outline-none focus:ring-2 focus:ring-[#4D007B]" />
       </td>
+      <td className="px-3 py-2">
+        <select aria-label="Public privacy" value={form.privacy} onChange={set('privacy')} className="border border-gray-300 rounded px-2 py-1 text-sm focus:outline-none focus:ring-2 focus:ring-[#4D007B]">
+          <option value="full">Full name</option>
+          <option value="initials">Initials</option>
+          <option value="hidden">Hidden</option>
+        </select>
+      </td>
       <td className="px-3 py-2 flex gap-2">
         <button onClick={() => onSave(form)} className="px-3 py-1 bg-[#4D007B] text-white rounded text-xs font-semibold hover:bg-[#3a0059] focus-visible:outline-[#FFD700]">Save</button>
         <button onClick={onCancel} className="px-3 py-1 bg-gray-200 text-gray-600 rounded text-xs font-semibold hover:bg-gray-300">Cancel</button>
@@ -107,14 +114,14 @@ function AthletesTab() {
   }
 
   async function handleEdit(id, form) {
-    await api.put(`/api/athletes?id=${id}`, form)
+    await api.put(`/api/athletes?id=${id}`, form, form.version)
     setEditId(null)
     load()
   }
 
-  async function handleDelete(id) {
+  async function handleDelete(id, version) {
     if (!confirm('Delete this athlete? Associated results will also be deleted.')) return
-    await api.del(`/api/athletes?id=${id}`)
+    await api.del(`/api/athletes?id=${id}`, version)
     load()
   }
 
@@ -133,6 +140,7 @@ function AthletesTab() {
               <th className="px-3 py-2 text-sm font-semibold">Grade</th>
               <th className="px-3 py-2 text-sm font-semibold">PR</th>
               <th className="px-3 py-2 text-sm font-semibold">Events</th>
+              <th className="px-3 py-2 text-sm font-semibold">Public</th>
               <th className="px-3 py-2 text-sm font-semibold">Actions</th>
             </tr>
           </thead>
@@ -148,9 +156,10 @@ function AthletesTab() {
                     <td className="px-3 py-2 text-sm text-gray-500">{a.grade}</td>
                     <td className="px-3 py-2 text-sm text-gray-500">{a.personal_record || '—'}</td>
                     <td className="px-3 py-2 text-sm text-gray-500">{a.events || '—'}</td>
+                    <td className="px-3 py-2 text-sm text-gray-500">{{ full: 'Full name', initials: 'Initials', hidden: 'Hidden' }[a.privacy] || '—'}</td>
                     <td className="px-3 py-2 flex gap-2">
                       <button onClick={() => setEditId(a.id)} aria-label={`Edit athlete ${a.name}`} className="text-xs px-2 py-1 bg-gray-100 rounded hover:bg-gray-200 text-gray-700">Edit</button>
-                      <button onClick={() => handleDelete(a.id)} aria-label={`Delete athlete ${a.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
+                      <button onClick={() => handleDelete(a.id, a.version)} aria-label={`Delete athlete ${a.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
                     </td>
                   </tr>
                 )
@@ -165,7 +174,7 @@ function AthletesTab() {
 // ─── Meets tab ─────────────────────────────────────────────────
 
 function emptyMeet() {
-  return { name: '', date: '', location: '', description: '' }
+  return { name: '', date: '', location: '', description: '', distance_m: '' }
 }
 
 function MeetForm({ initial, onSave, onCancel }) {
@@ -186,6 +195,9 @@ function MeetForm({ initial, onSave, onCancel }) {
       <td className="px-3 py-2">
         <input aria-label="Description" value={form.description} onChange={set('description')} placeholder="Description" className="w-full border border-gray-300 rounded px-2 py-1 text-sm focus:outline-none focus:ring-2 focus:ring-[#4D007B]" />
       </td>
+      <td className="px-3 py-2">
+        <input aria-label="Distance in meters" type="number" min="0" value={form.distance_m ?? ''} onChange={set('distance_m')} placeholder="5000" className="w-24 border border-gray-300 rounded px-2 py-1 text-sm focus:outline-none focus:ring-2 focus:ring-[#4D007B]" />
+      </td>
       <td className="px-3 py-2 flex gap-2">
         <button onClick={() => onSave(form)} className="px-3 py-1 bg-[#4D007B] text-white rounded text-xs font-semibold hover:bg-[#3a0059] focus-visible:outline-[#FFD700]">Save</button>
         <button onClick={onCancel} className="px-3 py-1 bg-gray-200 text-gray-600 rounded text-xs font-semibold hover:bg-gray-300">Cancel</button>
@@ -207,20 +219,20 @@ function MeetsTab() {
   useEffect(() => { load() }, [load])
 
   async function handleAdd(form) {
-    await api.post('/api/meets', form)
+    await api.post('/api/meets', { ...form, distance_m: Number(form.distance_m) || 0 })
     setAdding(false)
     load()
   }
 
   async function handleEdit(id, form) {
-    await api.put(`/api/meets?id=${id}`, form)
+    await api.put(`/api/meets?id=${id}`, { ...form, distance_m: Number(form.distance_m) || 0 }, form.version)
     setEditId(null)
     load()
   }
 
-  async function handleDelete(id) {
+  async function handleDelete(id, version) {
     if (!confirm('Delete this meet? Associated results will also be deleted.')) return
-    await api.del(`/api/meets?id=${id}`)
+    await api.del(`/api/meets?id=${id}`, version)
     load()
   }
 
@@ -238,6 +250,7 @@ function MeetsTab() {
               <th className="px-3 py-2 text-sm font-semibold">Date</th>
               <th className="px-3 py-2 text-sm font-semibold">Location</th>
               <th className="px-3 py-2 text-sm font-semibold hidden sm:table-cell">Description</th>
+              <th className="px-3 py-2 text-sm font-semibold">Distance (m)</th>
               <th className="px-3 py-2 text-sm font-semibold">Actions</th>
             </tr>
           </thead>
@@ -252,9 +265,10 @@ function MeetsTab() {
                     <td className="px-3 py-2 text-sm text-gray-500">{m.date}</td>
                     <td className="px-3 py-2 text-sm text-gray-500">{m.location || '—'}</td>
                     <td className="px-3 py-2 text-sm text-gray-500 hidden sm:table-cell">{m.description || '—'}</td>
+                    <td className="px-3 py-2 text-sm text-gray-500">{m.distance_m || '—'}</td>
                     <td className="px-3 py-2 flex gap-2">
                       <button onClick={() => setEditId(m.id)} aria-label={`Edit meet ${m.name}`} className="text-xs px-2 py-1 bg-gray-100 rounded hover:bg-gray-200 text-gray-700">Edit</button>
-                      <button onClick={() => handleDelete(m.id)} aria-label={`Delete meet ${m.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
+                      <button onClick={() => handleDelete(m.id, m.version)} aria-label={`Delete meet ${m.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
                     </td>
                   </tr>
                 )
@@ -334,14 +348,14 @@ function ResultsTab() {
   }
 
   async function handleEdit(id, form) {
-    await api.put(`/api/results?id=${id}`, { ...form, athleteId: Number(form.athleteId), meetId: Number(form.meetId), place: Number(form.place) })
+    await api.put(`/api/results?id=${id}`, { ...form, athleteId: Number(form.athleteId), meetId: Number(form.meetId), place: Number(form.place) }, form.version)
     setEditId(null)
     load()
   }
 
-  async function handleDelete(id) {
+  async function handleDelete(id, version) {
     if (!confirm('Delete this result?')) return
-    await api.del(`/api/results?id=${id}`)
+    await api.del(`/api/results?id=${id}`, version)
     load()
   }
 
@@ -375,7 +389,7 @@ function ResultsTab() {
                     <td className="px-3 py-2 text-sm text-gray-500">{r.place}</td>
                     <td className="px-3 py-2 flex gap-2">
                       <button onClick={() => setEditId(r.id)} aria-label={`Edit result for ${athleteMap[r.athleteId]?.name || 'athlete'} at ${meetMap[r.meetId]?.name || 'meet'}`} className="text-xs px-2 py-1 bg-gray-100 rounded hover:bg-gray-200 text-gray-700">Edit</button>
-                      <button onClick={() => handleDelete(r.id)} aria-label={`Delete result for ${athleteMap[r.athleteId]?.name || 'athlete'} at ${meetMap[r.meetId]?.name || 'meet'}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
+                      <button onClick={() => handleDelete(r.id, r.version)} aria-label={`Delete result for ${athleteMap[r.athleteId]?.name || 'athlete'} at ${meetMap[r.meetId]?.name || 'meet'}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
                     </td>
                   </tr>
                 )
@@ -435,14 +449,14 @@ function CoachesTab() {
   }
 
   async function handleEdit(id, form) {
-    await api.put(`/api/coaches?id=${id}`, form)
+    await api.put(`/api/coaches?id=${id}`, form, form.version)
     setEditId(null)
     load()
   }
 
-  async function handleDelete(id) {
+  async function handleDelete(id, version) {
     if (!confirm('Delete this coach?')) return
-    await api.del(`/api/coaches?id=${id}`)
+    await api.del(`/api/coaches?id=${id}`, version)
     load()
   }
 
@@ -474,7 +488,7 @@ function CoachesTab() {
                     <td className="px-3 py-2 text-sm text-gray-500 hidden sm:table-cell">{c.bio || '—'}</td>
                     <td className="px-3 py-2 flex gap-2">
                       <button onClick={() => setEditId(c.id)} aria-label={`Edit coach ${c.name}`} className="text-xs px-2 py-1 bg-gray-100 rounded hover:bg-gray-200 text-gray-700">Edit</button>
-                      <button onClick={() => handleDelete(c.id)} aria-label={`Delete coach ${c.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
+                      <button onClick={() => handleDelete(c.id, c.version)} aria-label={`Delete coach ${c.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
                     </td>
                   </tr>
                 )
@@ -540,14 +554,14 @@ function FutureMeetsTab() {
   }
 
   async function handleEdit(id, form) {
-    await api.put(`/api/future-meets?id=${id}`, form)
+    await api.put(`/api/future-meets?id=${id}`, form, form.version)
     setEditId(null)
     load()
   }
 
-  async function handleDelete(id) {
+  async function handleDelete(id, version) {
     if (!confirm('Delete this future meet?')) return
-    await api.del(`/api/future-meets?id=${id}`)
+    await api.del(`/api/future-meets?id=${id}`, version)
     load()
   }
 
@@ -585,7 +599,7 @@ function FutureMeetsTab() {
                     </td>
                     <td className="px-3 py-2 flex gap-2">
                       <button onClick={() => setEditId(m.id)} aria-label={`Edit future meet ${m.name}`} className="text-xs px-2 py-1 bg-gray-100 rounded hover:bg-gray-200 text-gray-700">Edit</button>
-                      <button onClick={() => handleDelete(m.id)} aria-label={`Delete future meet ${m.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
+                      <button onClick={() => handleDelete(m.id, m.version)} aria-label={`Delete future meet ${m.name}`} className="text-xs px-2 py-1 bg-red-100 rounded hover:bg-red-200 text-red-600">Delete</button>
                     </td>
                   </tr>
                 )
@@ -1,24 +1,41 @@
 import { useState } from 'react'
-import { useNavigate, Navigate } from 'react-router-dom'
+import { Link, useNavigate, Navigate } from 'react-router-dom'
 import { useAuth } from '../context/AuthContext'
 
+function homeFor(role) {
+  return role === 'athlete' || role === 'parent' ? '/me' : '/admin'
+}
+
 export default function Login() {
-  const { isAdmin, login } = useAuth()
+  const { user, isAdmin, login, verifyMfa } = useAuth()
   const navigate = useNavigate()
   const [username, setUsername] = useState('')
   const [password, setPassword] = useState('')
   const [error, setError] = useState(null)
   const [loading, setLoading] = useState(false)
+  const [mfaToken, setMfaToken] = useState(null)
+  const [code, setCode] = useState('')
 
-  if (isAdmin) return <Navigate to="/admin" replace />
+  if (user) return <Navigate to={isAdmin ? '/admin' : '/me'} replace />
 
   async function handleSubmit(e) {
     e.preventDefault()
     setError(null)
     setLoading(true)
     try {
-      await login(username, password)
-      navigate('/admin')
+      if (mfaToken) {
+        // Six digits from the authenticator app; anything else is a recovery code.
+        const trimmed = code.trim()
+        const { role } = await verifyMfa(mfaToken, username, /^\d{6}$/.test(trimmed) ? { code: trimmed } : { recoveryCode: trimmed })
+        navigate(homeFor(role))
+        return
+      }
+      const { mfaToken: pending, role } = await login(username, password)
+      if (pending) {
+        setMfaToken(pending)
+        return
+      }
+      navigate(homeFor(role))
     } catch (err) {
       setError(err.message)
     } finally {
@@ -30,8 +47,8 @@ export default function Login() {
     <div className="max-w-md mx-auto mt-8 sm:mt-16">
       <div className="bg-white rounded-xl shadow-lg overflow-hidden">
         <div className="bg-[#4D007B] px-4 sm:px-8 py-4 sm:py-6">
-          <h1 className="text-2xl font-bold text-white">Admin Login</h1>
-          <p className="text-gray-300 text-sm mt-1">Sign in to manage team data</p>
+          <h1 className="text-2xl font-bold text-white">Login</h1>
+          <p className="text-gray-300 text-sm mt-1">Coaches, athletes and parents sign in here</p>
         </div>
         <form onSubmit={handleSubmit} className="p-4 sm:p-8 flex flex-col gap-4">
           <div>
@@ -60,6 +77,25 @@ export default function Login() {
               className="w-full px-3 py-2 rounded-lg border border-gray-300 focus:outline-none focus:ring-2 focus:ring-[#4D007B]"
             />
           </div>
+          {mfaToken && (
+            <div>
+              <label htmlFor="mfa-code" className="block text-sm font-medium text-gray-700 mb-1">Authentication code</label>
+              <input
+                id="mfa-code"
+                type="text"
+                inputMode="numeric"
+                autoComplete="one-time-code"
+                autoFocus
+                value={code}
+                onChange={e => setCode(e.target.value)}
+                required
+                aria-invalid={error ? "true" : "false"}
+                aria-describedby="mfa-help"
+                className="w-full px-3 py-2 rounded-lg border border-gray-300 focus:outline-none focus:ring-2 focus:ring-[#4D007B]"
+              />
+              <p id="mfa-help" className="text-gray-500 text-xs mt-1">Enter the 6-digit code from your authenticator app, or a recovery code.</p>
+            </div>
+          )}
           {error && <p id="login-error" className="text-red-600 text-sm" role="alert">{error}</p>}
           <button
             type="submit"
@@ -67,8 +103,13 @@ export default function Login() {
             aria-live="polite"
             className="w-full py-3 rounded-lg bg-[#4D007B] text-white font-semibold hover:bg-[#3a0059] transition-colors disabled:opacity-50 focus-visible:outline-2 focus-visible:outline-[#FFD700] min-h-[44px]"
           >
-            {loading ? 'Signing in...' : 'Sign In'}
+            {loading ? 'Signing in...' : mfaToken ? 'Verify' : 'Sign In'}
           </button>
+          {!mfaToken && (
+            <Link to="/reset-password" className="text-sm text-center text-[#4D007B] hover:underline">
+              Forgot your password?
+            </Link>
+          )}
         </form>
       </div>
     </div>
@@ -50,9 +50,12 @@ export default function MeetDetail() {
   // Split results by gender based on athlete lookup
   const boysResults = results.filter(r => athleteMap[r.athleteId]?.gender === 'M')
   const girlsResults = results.filter(r => athleteMap[r.athleteId]?.gender === 'F')
+  // Athletes whose privacy setting hides their gender
+  const otherResults = results.filter(r => !['M', 'F'].includes(athleteMap[r.athleteId]?.gender))
 
   function ResultsTable({ rows, title }) {
     if (rows.length === 0) return null
+    const hasSplits = rows.some(r => r.splits?.length)
     return (
       <div>
         <h2 className="text-lg font-semibold text-[#4D007B] mb-2">{title}</h2>
@@ -66,6 +69,7 @@ export default function MeetDetail() {
                 <th scope="col" className="px-2 sm:px-6 py-2 sm:py-3 text-left text-sm font-semibold">Athlete</th>
                 <th scope="col" className="px-2 sm:px-6 py-2 sm:py-3 text-left text-sm font-semibold hidden sm:table-cell">Grade</th>
                 <th scope="col" className="px-2 sm:px-6 py-2 sm:py-3 text-left text-sm font-semibold">Time</th>
+                {hasSplits && <th scope="col" className="px-2 sm:px-6 py-2 sm:py-3 text-left text-sm font-semibold hidden sm:table-cell">Splits</th>}
               </tr>
             </thead>
             <tbody className="divide-y divide-gray-100">
@@ -77,6 +81,15 @@ export default function MeetDetail() {
                     <td className="px-2 sm:px-6 py-2 sm:py-3 text-sm text-gray-900">{athlete?.name || '—'}</td>
                     <td className="px-2 sm:px-6 py-2 sm:py-3 text-sm text-gray-700 hidden sm:table-cell">{athlete?.grade || '—'}</td>
                     <td className="px-2 sm:px-6 py-2 sm:py-3 text-sm text-gray-700">{r.time}</td>
+                    {hasSplits && (
+                      <td className="px-2 sm:px-6 py-2 sm:py-3 text-xs text-gray-500 hidden sm:table-cell">
+                        {(r.splits || []).map(s => (
+                          <span key={s.id} className="block" title={`${s.label}: ${s.lap} lap, ${s.lap_pace}/mi${s.differential ? ` (${s.differential})` : ''}`}>
+                            {s.label} {s.elapsed}
+                          </span>
+                        ))}
+                      </td>
+                    )}
                   </tr>
                 )
               })}
@@ -108,6 +121,7 @@ export default function MeetDetail() {
         <div className="grid grid-cols-1 md:grid-cols-2 gap-6">
           <ResultsTable rows={boysResults} title="Boys Results" />
           <ResultsTable rows={girlsResults} title="Girls Results" />
+          <ResultsTable rows={otherResults} title="Other Results" />
         </div>
       )}
     </div>
@@ -0,0 +1,161 @@
+import { useState, useEffect } from 'react'
+import { useApi } from '../hooks/useApi'
+
+// Read-only view for athlete and parent accounts: the athlete's profile, race
+// results, goal progress, weekly mileage, attendance and the coach notes that
+// aren't shown publicly.
+export default function MyAthlete() {
+  const { get } = useApi()
+  const [me, setMe] = useState(null)
+  const [notes, setNotes] = useState([])
+  const [results, setResults] = useState([])
+  const [meetMap, setMeetMap] = useState({})
+  const [goals, setGoals] = useState([])
+  const [weeks, setWeeks] = useState([])
+  const [attendance, setAttendance] = useState(null)
+  const [loading, setLoading] = useState(true)
+  const [error, setError] = useState(null)
+
+  useEffect(() => {
+    Promise.all([get('/api/me'), get('/api/me/notes'), get('/api/me/goals'), get('/api/me/workouts?weekly=true'), get('/api/me/attendance'), get('/api/me/results'), get('/api/meets')])
+      .then(([profile, n, progress, weekly, att, res, meets]) => {
+        setMe(profile)
+        setNotes(n)
+        setResults(res)
+        const map = {}
+        meets.forEach(m => { map[m.id] = m })
+        setMeetMap(map)
+        setGoals(progress.goals)
+        setWeeks(weekly)
+        setAttendance(att)
+        setLoading(false)
+      })
+      .catch(err => { setError(err.message); setLoading(false) })
+  }, [])
+
+  if (loading) return <div role="status" className="text-center py-12 text-gray-500">Loading...</div>
+  if (error) return <div role="alert" className="text-center py-12 text-red-500">Error: {error}</div>
+
+  const athlete = me.athlete
+  return (
+    <div>
+      <div className="bg-[#4D007B] text-white rounded-xl p-4 sm:p-6 mb-6">
+        <h1 className="text-2xl font-bold">{athlete.name}</h1>
+        <p className="text-gray-300 mt-1">
+          Grade {athlete.grade}
+          {athlete.personal_record && <> &middot; PR {athlete.personal_record}</>}
+        </p>
+        {attendance?.percent != null && (
+          <p className="text-gray-300 text-sm">
+            Practice attendance {attendance.percent}% over the last 8 weeks
+          </p>
+        )}
+        {me.role === 'parent' && <p className="text-gray-300 text-sm">Signed in as a parent ({me.username})</p>}
+      </div>
+
+      {results.length > 0 && (
+        <>
+          <h2 className="text-lg font-semibold text-[#4D007B] mb-2">Results</h2>
+          <div className="bg-white rounded-xl shadow overflow-x-auto mb-6">
+            <table className="min-w-full text-left">
+              <caption className="sr-only">Race results</caption>
+              <thead>
+                <tr className="bg-[#4D007B] text-white">
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold">Meet</th>
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold">Date</th>
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold">Place</th>
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold">Time</th>
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold hidden sm:table-cell">Splits</th>
+                </tr>
+              </thead>
+              <tbody className="divide-y divide-gray-100">
+                {results.map(r => (
+                  <tr key={r.id}>
+                    <td className="px-3 py-2 text-sm text-gray-900">{meetMap[r.meetId]?.name || '—'}</td>
+                    <td className="px-3 py-2 text-sm text-gray-700">{meetMap[r.meetId]?.date || '—'}</td>
+                    <td className="px-3 py-2 text-sm text-gray-700">{r.place || '—'}</td>
+                    <td className="px-3 py-2 text-sm text-gray-700">{r.time}</td>
+                    <td className="px-3 py-2 text-xs text-gray-500 hidden sm:table-cell">
+                      {(r.splits || []).map(s => (
+                        <span key={s.id} className="block" title={`${s.label}: ${s.lap} lap, ${s.lap_pace}/mi`}>
+                          {s.label} {s.elapsed}
+                        </span>
+                      ))}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        </>
+      )}
+
+      {goals.length > 0 && (
+        <>
+          <h2 className="text-lg font-semibold text-[#4D007B] mb-2">Goals</h2>
+          <ul className="flex flex-col gap-3 mb-6">
+            {goals.map(g => (
+              <li key={g.id} className="bg-white rounded-xl shadow p-4">
+                <p className="font-semibold text-gray-900">
+                  {g.label}: {g.target_time}
+                  {g.target_meet && <span className="font-normal text-gray-500"> at {g.target_meet}</span>}
+                </p>
+                {g.season_best ? (
+                  <p className="text-sm text-gray-700 mt-1">
+                    Season best {g.season_best} ({g.met ? 'goal met' : `${g.gap} to go`})
+                    &middot; projected {g.predicted}{g.on_track && !g.met && ' — on track'}
+                  </p>
+                ) : (
+                  <p className="text-sm text-gray-500 mt-1">No races yet this season.</p>
+                )}
+              </li>
+            ))}
+          </ul>
+        </>
+      )}
+
+      {weeks.length > 0 && (
+        <>
+          <h2 className="text-lg font-semibold text-[#4D007B] mb-2">Weekly Mileage</h2>
+          <div className="bg-white rounded-xl shadow overflow-x-auto mb-6">
+            <table className="min-w-full text-left">
+              <caption className="sr-only">Weekly mileage</caption>
+              <thead>
+                <tr className="bg-[#4D007B] text-white">
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold">Week of</th>
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold">Miles</th>
+                  <th scope="col" className="px-3 py-2 text-sm font-semibold">Workouts</th>
+                </tr>
+              </thead>
+              <tbody className="divide-y divide-gray-100">
+                {weeks.map(w => (
+                  <tr key={w.week_start}>
+                    <td className="px-3 py-2 text-sm text-gray-900">{w.week_start}</td>
+                    <td className="px-3 py-2 text-sm text-gray-700">{w.miles}</td>
+                    <td className="px-3 py-2 text-sm text-gray-700">{w.workouts}</td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        </>
+      )}
+
+      <h2 className="text-lg font-semibold text-[#4D007B] mb-2">Coach Notes</h2>
+      {notes.length === 0 ? (
+        <p className="text-center text-gray-400 py-8">No notes yet.</p>
+      ) : (
+        <ul className="flex flex-col gap-3">
+          {notes.map(n => (
+            <li key={n.id} className="bg-white rounded-xl shadow p-4">
+              <p className="text-gray-900 whitespace-pre-line">{n.body}</p>
+              <p className="text-gray-500 text-xs mt-2">
+                {n.author} &middot; {new Date(n.created_at).toLocaleDateString('en-US')}
+              </p>
+            </li>
+          ))}
+        </ul>
+      )}
+    </div>
+  )
+}
@@ -0,0 +1,105 @@
+import { useState } from 'react'
+import { Link, useSearchParams } from 'react-router-dom'
+
+const inputClass = 'w-full px-3 py-2 rounded-lg border border-gray-300 focus:outline-none focus:ring-2 focus:ring-[#4D007B]'
+
+// Without a token this asks for the account's email; the emailed link comes
+// back here with ?token= to choose the new password.
+export default function ResetPassword() {
+  const [params] = useSearchParams()
+  const token = params.get('token')
+  const [email, setEmail] = useState('')
+  const [password, setPassword] = useState('')
+  const [message, setMessage] = useState(null)
+  const [error, setError] = useState(null)
+  const [loading, setLoading] = useState(false)
+  const [done, setDone] = useState(false)
+
+  async function handleSubmit(e) {
+    e.preventDefault()
+    setError(null)
+    setLoading(true)
+    try {
+      const res = await fetch(token ? '/api/password-reset/confirm' : '/api/password-reset', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(token ? { token, password } : { email }),
+      })
+      if (!res.ok) {
+        const data = await res.json()
+        const field = data.fields?.[0]
+        throw new Error(field ? `${field.field} ${field.message}` : data.error || 'Request failed')
+      }
+      if (token) {
+        setDone(true)
+      } else {
+        setMessage((await res.json()).message)
+      }
+    } catch (err) {
+      setError(err.message)
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="max-w-md mx-auto mt-8 sm:mt-16">
+      <div className="bg-white rounded-xl shadow-lg overflow-hidden">
+        <div className="bg-[#4D007B] px-4 sm:px-8 py-4 sm:py-6">
+          <h1 className="text-2xl font-bold text-white">Reset Password</h1>
+          <p className="text-gray-300 text-sm mt-1">
+            {token ? 'Choose a new password' : "We'll email you a link to reset it"}
+          </p>
+        </div>
+        {done ? (
+          <div className="p-4 sm:p-8 flex flex-col gap-4">
+            <p className="text-gray-700" role="status">Your password has been changed and other sessions were signed out.</p>
+            <Link to="/login" className="text-[#4D007B] font-semibold hover:underline">Sign in</Link>
+          </div>
+        ) : (
+          <form onSubmit={handleSubmit} className="p-4 sm:p-8 flex flex-col gap-4">
+            {token ? (
+              <div>
+                <label htmlFor="new-password" className="block text-sm font-medium text-gray-700 mb-1">New password</label>
+                <input
+                  id="new-password"
+                  type="password"
+                  autoComplete="new-password"
+                  minLength={12}
+                  value={password}
+                  onChange={e => setPassword(e.target.value)}
+                  required
+                  aria-describedby="password-help"
+                  className={inputClass}
+                />
+                <p id="password-help" className="text-gray-500 text-xs mt-1">At least 12 characters.</p>
+              </div>
+            ) : (
+              <div>
+                <label htmlFor="email" className="block text-sm font-medium text-gray-700 mb-1">Email</label>
+                <input
+                  id="email"
+                  type="email"
+                  autoComplete="email"
+                  value={email}
+                  onChange={e => setEmail(e.target.value)}
+                  required
+                  className={inputClass}
+                />
+              </div>
+            )}
+            {message && <p className="text-green-700 text-sm" role="status">{message}</p>}
+            {error && <p className="text-red-600 text-sm" role="alert">{error}</p>}
+            <button
+              type="submit"
+              disabled={loading || message !== null}
+              className="w-full py-3 rounded-lg bg-[#4D007B] text-white font-semibold hover:bg-[#3a0059] transition-colors disabled:opacity-50 focus-visible:outline-2 focus-visible:outline-[#FFD700] min-h-[44px]"
+            >
+              {loading ? 'Sending...' : token ? 'Set Password' : 'Send Reset Link'}
+            </button>
+          </form>
+        )}
+      </div>
+    </div>
+  )
+}